@@ -0,0 +1,125 @@
+// accelerations.go calculates the accelerations of many stars at once using the tree
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// accelerationBlockSize is the amount of stars a worker handles at once
+const accelerationBlockSize = 64
+
+// WithTreeOrder lets ComputeAccelerations walk the tree for the stars in the order of their morton keys instead of
+// the order they were given in. Stars next to each other visit mostly the same nodes, so the nodes are more likely
+// to still be cached. The accelerations are returned in the order of the given stars anyway.
+func WithTreeOrder() ForceOption {
+	return func(options *forceOptions) {
+		options.treeOrder = true
+	}
+}
+
+// ComputeAccelerations calculates the acceleration of every given star using the tree it is called on and the given
+// theta. The tree is walked once for every star, the stars are distributed in between the given amount of workers.
+// If workers is smaller than 1, GOMAXPROCS workers are used. The options are the same as the ones of CalcAllForces.
+// The acceleration of a star without mass is the acceleration a star of mass 1 would experience at its position.
+// The RelativeErrorCriterion gets the previous acceleration of every star from WithPreviousAccelerations, a single
+// acceleration given using WithPreviousAcceleration is ignored as it can't belong to all the stars. ComputeAccelerations
+// panics if the amount of previous accelerations doesn't match the amount of stars.
+// The tree must not be modified while the accelerations are calculated.
+func (n *BodyNode[B]) ComputeAccelerations(stars []B, theta float64, workers int, opts ...ForceOption) []Vec2 {
+	options := newForceOptions(opts)
+	options.previousAcceleration = Vec2{}
+	if options.previousAccelerations != nil && len(options.previousAccelerations) != len(stars) {
+		panic(fmt.Sprintf("structs: got %v previous accelerations for %v stars",
+			len(options.previousAccelerations), len(stars)))
+	}
+
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	// define the order in which the stars are handled
+	order := make([]int, len(stars))
+	for i := range order {
+		order[i] = i
+	}
+	if options.treeOrder {
+		keys := make([]uint64, len(stars))
+		for i, star := range stars {
+			keys[i] = mortonKey(star.Position(), n.Boundary)
+		}
+		sort.Slice(order, func(i, j int) bool {
+			if keys[order[i]] == keys[order[j]] {
+				return order[i] < order[j]
+			}
+			return keys[order[i]] < keys[order[j]]
+		})
+	}
+
+	// distribute the blocks of stars in between the workers
+	blocks := make(chan int)
+	go func() {
+		for start := 0; start < len(stars); start += accelerationBlockSize {
+			blocks <- start
+		}
+		close(blocks)
+	}()
+
+	accelerations := make([]Vec2, len(stars))
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for start := range blocks {
+				end := start + accelerationBlockSize
+				if end > len(stars) {
+					end = len(stars)
+				}
+
+				starOptions := options
+				for _, i := range order[start:end] {
+					if options.previousAccelerations != nil {
+						starOptions.previousAcceleration = options.previousAccelerations[i]
+					}
+					accelerations[i] = n.calcAcceleration(bodyStar(stars[i]), theta, &starOptions)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	return accelerations
+}
+
+// calcAcceleration calculates the acceleration of the given star using the given options
+func (n *BodyNode[B]) calcAcceleration(star Star2D, theta float64, options *forceOptions) Vec2 {
+
+	// a star without mass doesn't experience any force, so a star of mass 1 is used instead
+	if star.M == 0 {
+		star.M = 1
+		return n.calcAllForces(star, theta, options)
+	}
+
+	force := n.calcAllForces(star, theta, options)
+	return force.Multiply(1 / star.M)
+}
@@ -0,0 +1,148 @@
+// accelerations_test.go provides tests for accelerations.go
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// ComputeAccelerations calculates the accelerations of all the stars in a single call
+func ExampleNode_ComputeAccelerations() {
+	stars := []Star2D{
+		NewStar2D(Vec2{0, 0}, Vec2{0, 0}, 1),
+		NewStar2D(Vec2{3, 4}, Vec2{0, 0}, 2),
+	}
+
+	// There will be no error handling here, we'll assume that everything goes right..
+	root, _ := BuildTree(stars)
+
+	accelerations := root.ComputeAccelerations(stars, 0.5, 2, WithUnits(NBodyUnits))
+	fmt.Printf("%.2f\n", accelerations)
+	// Output:
+	// [{0.05 0.06} {-0.02 -0.03}]
+}
+
+func TestNode_ComputeAccelerations(t *testing.T) {
+	stars := randomStars(2000, 100, 20)
+	root, err := BuildTree(stars)
+	if err != nil {
+		t.Fatalf("BuildTree() error = %v", err)
+	}
+
+	// stars that aren't part of the tree can be given as well
+	probes := append([]Star2D{
+		NewStar2D(Vec2{10, 10}, Vec2{0, 0}, 0),
+		NewStar2D(Vec2{1000, -300}, Vec2{0, 0}, 5),
+	}, stars...)
+
+	tests := []struct {
+		name    string
+		workers int
+		opts    []ForceOption
+	}{
+		{
+			name:    "single worker",
+			workers: 1,
+		},
+		{
+			name:    "many workers",
+			workers: 8,
+		},
+		{
+			name:    "default amount of workers in tree order",
+			workers: 0,
+			opts:    []ForceOption{WithTreeOrder()},
+		},
+		{
+			name:    "many workers using all the options",
+			workers: 4,
+			opts: []ForceOption{WithTreeOrder(), WithQuadrupole(), WithSoftening(PlummerSoftening, 1),
+				WithOpeningCriterion(BmaxCriterion), WithUnits(GalacticUnits)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := root.ComputeAccelerations(probes, 0.7, tt.workers, tt.opts...)
+			if len(got) != len(probes) {
+				t.Fatalf("Node.ComputeAccelerations() returned %v accelerations, want %v", len(got), len(probes))
+			}
+
+			for i, star := range probes {
+				mass := star.M
+				if mass == 0 {
+					star.M = 1
+					mass = 1
+				}
+
+				want := root.CalcAllForces(star, 0.7, tt.opts...)
+				want = want.Multiply(1 / mass)
+				if got[i] != want {
+					t.Errorf("Node.ComputeAccelerations() = %v for star %v, want %v", got[i], i, want)
+				}
+			}
+		})
+	}
+
+	// opening every cell results in the direct sum
+	got := root.ComputeAccelerations(stars, 0, 0)
+	want, _ := DirectSum(stars, 0)
+	for i := range stars {
+		if math.Hypot(got[i].X-want[i].X, got[i].Y-want[i].Y) > 1e-9*math.Hypot(want[i].X, want[i].Y) {
+			t.Errorf("Node.ComputeAccelerations() = %v, want %v", got[i], want[i])
+		}
+	}
+}
+
+func TestNode_ComputeAccelerations_previousAccelerations(t *testing.T) {
+	stars := randomStars(500, 100, 21)
+	root, err := BuildTree(stars)
+	if err != nil {
+		t.Fatalf("BuildTree() error = %v", err)
+	}
+	previous, _ := DirectSum(stars, 0)
+
+	// every star uses its own previous acceleration
+	got := root.ComputeAccelerations(stars, 0.01, 3, WithOpeningCriterion(RelativeErrorCriterion),
+		WithPreviousAccelerations(previous), WithTreeOrder())
+	for i, star := range stars {
+		want := root.CalcAllForces(star, 0.01, WithOpeningCriterion(RelativeErrorCriterion),
+			WithPreviousAcceleration(previous[i]))
+		want = want.Multiply(1 / star.M)
+		if got[i] != want {
+			t.Errorf("Node.ComputeAccelerations() = %v for star %v, want %v", got[i], i, want)
+		}
+	}
+
+	// a single previous acceleration is ignored
+	got = root.ComputeAccelerations(stars, 0.7, 3, WithOpeningCriterion(RelativeErrorCriterion),
+		WithPreviousAcceleration(Vec2{1e9, 0}))
+	want := root.ComputeAccelerations(stars, 0.7, 3, WithOpeningCriterion(RelativeErrorCriterion))
+	if reflect.DeepEqual(got, want) == false {
+		t.Errorf("Node.ComputeAccelerations() uses the single previous acceleration for all the stars")
+	}
+
+	// the amount of previous accelerations has to match the amount of stars
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Node.ComputeAccelerations() didn't panic using too few previous accelerations")
+		}
+	}()
+	root.ComputeAccelerations(stars, 0.7, 3, WithPreviousAccelerations(previous[1:]))
+}
@@ -0,0 +1,233 @@
+// blockTimesteps.go advances stars using individual timesteps organized in power of two bins
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+import (
+	"fmt"
+	"math"
+)
+
+// TimestepCriterion selects the timestep of a single star
+type TimestepCriterion interface {
+
+	// Timestep returns the timestep the star should use. The acceleration is the current acceleration of the star,
+	// the jerk is the rate of change of the acceleration during the last step of the star ({0 0} before the first
+	// step). An infinite timestep lets the star use the largest timestep available.
+	Timestep(star Star2D, acceleration Vec2, jerk Vec2) float64
+}
+
+// PowerCriterion selects the timestep eta*sqrt(eps/|a|) using the softening length eps (Power et al. 2003)
+type PowerCriterion struct {
+	Eta       float64 // Accuracy parameter, usually in between 0.1 and 0.3
+	Softening float64 // Softening length used to calculate the forces
+}
+
+// Timestep returns eta*sqrt(eps/|a|)
+func (c PowerCriterion) Timestep(star Star2D, acceleration Vec2, jerk Vec2) float64 {
+	return c.Eta * math.Sqrt(c.Softening/acceleration.Len())
+}
+
+// AarsethCriterion selects the timestep eta*|a|/|da/dt| using the first order form of the criterion of Aarseth.
+// The rate of change of the acceleration is estimated using the change of the acceleration during the last step
+// of the star. Before the first step it is unknown, so the star uses the smallest timestep available.
+type AarsethCriterion struct {
+	Eta float64 // Accuracy parameter, usually around 0.02
+}
+
+// Timestep returns eta*|a|/|da/dt| or 0 if the rate of change of the acceleration is unknown
+func (c AarsethCriterion) Timestep(star Star2D, acceleration Vec2, jerk Vec2) float64 {
+	if jerk == (Vec2{}) {
+		return 0
+	}
+	return c.Eta * acceleration.Len() / jerk.Len()
+}
+
+// BlockTimesteps advances stars using individual timesteps. The timestep of every star is a power of two fraction
+// MaxTimestep/2^level of the largest timestep, so the stars are sorted into bins. A bin is advanced using
+// kick-drift-kick leapfrog steps: the stars of a bin are only kicked at the start and the end of their own step,
+// in between they drift along with the other stars. The forces are only calculated for the stars whose step ends,
+// the tree storing the stars is kept up to date using Node.Update, so the moments are only recalculated along the
+// paths of the moved stars instead of rebuilding the tree.
+type BlockTimesteps struct {
+	MaxTimestep float64           // Timestep of the coarsest bin (level 0)
+	MaxLevel    int               // Level of the finest bin using the timestep MaxTimestep/2^MaxLevel
+	Criterion   TimestepCriterion // Criterion selecting the timestep of the stars
+	Theta       float64           // Opening angle used to calculate the forces
+	Workers     int               // Amount of workers used to calculate the forces
+	Options     []ForceOption     // Options used to calculate the forces
+
+	root          *Node
+	stars         []Star2D // current state of the stars
+	treeStars     []Star2D // state of the stars as stored in the tree
+	accelerations []Vec2   // accelerations at the start of the current step of every star
+	jerks         []Vec2   // change of the accelerations during the last step of every star
+	levels        []int    // bin of every star
+	time          float64  // time the stars are synchronized at
+}
+
+// NewBlockTimesteps builds a tree out of the given stars, calculates their accelerations and sorts them into bins
+// using the given criterion. The tree automatically expands if stars leave it.
+func NewBlockTimesteps(stars []Star2D, maxTimestep float64, maxLevel int, criterion TimestepCriterion, theta float64, opts ...ForceOption) (*BlockTimesteps, error) {
+	root, err := BuildTree(stars, WithTreeConfig(TreeConfig{AutoExpand: true}))
+	if err != nil {
+		return nil, err
+	}
+
+	b := &BlockTimesteps{
+		MaxTimestep: maxTimestep,
+		MaxLevel:    maxLevel,
+		Criterion:   criterion,
+		Theta:       theta,
+		Options:     opts,
+
+		root:      root,
+		stars:     append([]Star2D(nil), stars...),
+		treeStars: append([]Star2D(nil), stars...),
+		jerks:     make([]Vec2, len(stars)),
+		levels:    make([]int, len(stars)),
+	}
+
+	b.accelerations = root.ComputeAccelerations(b.stars, b.Theta, b.Workers, b.Options...)
+	for i := range b.stars {
+		b.levels[i] = b.level(i, 0)
+	}
+
+	return b, nil
+}
+
+// Stars returns a copy of the stars at the time they are synchronized at
+func (b *BlockTimesteps) Stars() []Star2D {
+	return append([]Star2D(nil), b.stars...)
+}
+
+// Levels returns a copy of the bins the stars are sorted into
+func (b *BlockTimesteps) Levels() []int {
+	return append([]int(nil), b.levels...)
+}
+
+// Time returns the time the stars are synchronized at
+func (b *BlockTimesteps) Time() float64 {
+	return b.time
+}
+
+// Root returns the tree storing the stars
+func (b *BlockTimesteps) Root() *Node {
+	return b.root
+}
+
+// span returns the amount of the finest timesteps a step of the given level takes
+func (b *BlockTimesteps) span(level int) int {
+	return 1 << uint(b.MaxLevel-level)
+}
+
+// level returns the bin the star i should be sorted into at the given tick (counted in the finest timesteps since
+// the start of the step). The star is only moved into a coarser bin if the step of that bin starts at the tick.
+func (b *BlockTimesteps) level(i int, tick int) int {
+	timestep := b.Criterion.Timestep(b.stars[i], b.accelerations[i], b.jerks[i])
+
+	level := 0
+	if math.IsNaN(timestep) == false {
+		for level < b.MaxLevel && b.MaxTimestep/float64(int(1)<<uint(level)) > timestep {
+			level++
+		}
+	}
+
+	// the steps of all the bins have to end at the end of the largest step
+	for tick%b.span(level) != 0 {
+		level++
+	}
+
+	return level
+}
+
+// Step advances all the stars by MaxTimestep. Only the finest timesteps at which the step of at least one star ends
+// are evaluated, the stars are drifted up to those times in one go.
+func (b *BlockTimesteps) Step() error {
+	dt := b.MaxTimestep / float64(b.span(0))
+
+	for tick := 0; tick < b.span(0); {
+
+		// kick the stars whose step starts
+		for i := range b.stars {
+			if tick%b.span(b.levels[i]) == 0 {
+				b.stars[i].AccelerateVelocity(b.accelerations[i], b.timestep(i)/2)
+			}
+		}
+
+		// find the next tick the step of a star ends at
+		next := b.span(0)
+		for i := range b.stars {
+			end := (tick/b.span(b.levels[i]) + 1) * b.span(b.levels[i])
+			if end < next {
+				next = end
+			}
+		}
+
+		// drift all the stars and move them in the tree
+		for i := range b.stars {
+			b.stars[i].Move(float64(next-tick) * dt)
+		}
+		if err := b.sync(); err != nil {
+			return err
+		}
+
+		// calculate the new accelerations of the stars whose step ends and kick them
+		var active []int
+		var activeStars []Star2D
+		for i := range b.stars {
+			if next%b.span(b.levels[i]) == 0 {
+				active = append(active, i)
+				activeStars = append(activeStars, b.stars[i])
+			}
+		}
+
+		accelerations := b.root.ComputeAccelerations(activeStars, b.Theta, b.Workers, b.Options...)
+		for k, i := range active {
+			timestep := b.timestep(i)
+			b.jerks[i] = accelerations[k].Sub(b.accelerations[i]).Multiply(1 / timestep)
+			b.accelerations[i] = accelerations[k]
+			b.stars[i].AccelerateVelocity(b.accelerations[i], timestep/2)
+
+			b.levels[i] = b.level(i, next)
+		}
+
+		tick = next
+	}
+
+	b.time += b.MaxTimestep
+	return b.sync()
+}
+
+// timestep returns the timestep of the star i
+func (b *BlockTimesteps) timestep(i int) float64 {
+	return b.MaxTimestep / float64(int(1)<<uint(b.levels[i]))
+}
+
+// sync updates the stars stored in the tree that changed since they were stored
+func (b *BlockTimesteps) sync() error {
+	for i := range b.stars {
+		if b.stars[i] == b.treeStars[i] {
+			continue
+		}
+
+		if err := b.root.Update(b.treeStars[i], b.stars[i]); err != nil {
+			return fmt.Errorf("could not advance the stars: %w", err)
+		}
+		b.treeStars[i] = b.stars[i]
+	}
+	return nil
+}
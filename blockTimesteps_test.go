@@ -0,0 +1,207 @@
+// blockTimesteps_test.go provides tests for blockTimesteps.go
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// fixedTimestep is a TimestepCriterion selecting the same timestep for every star
+type fixedTimestep float64
+
+func (c fixedTimestep) Timestep(star Star2D, acceleration Vec2, jerk Vec2) float64 {
+	return float64(c)
+}
+
+// keplerStars returns a heavy star at the origin orbited by light stars on circular orbits of the given radii
+func keplerStars(radii []float64) []Star2D {
+	G := NBodyUnits.G()
+
+	stars := []Star2D{NewStar2D(Vec2{0, 0}, Vec2{0, 0}, 1)}
+	for i, radius := range radii {
+		angle := float64(i)
+		velocity := math.Sqrt(G / radius)
+		stars = append(stars, NewStar2D(
+			Vec2{radius * math.Cos(angle), radius * math.Sin(angle)},
+			Vec2{-velocity * math.Sin(angle), velocity * math.Cos(angle)},
+			1e-10,
+		))
+	}
+	return stars
+}
+
+// Stars close to the center of a galaxy are advanced using smaller timesteps
+func ExampleBlockTimesteps() {
+	stars := keplerStars([]float64{0.25, 1, 4})
+
+	criterion := PowerCriterion{Eta: 0.05, Softening: 0.01}
+	b, err := NewBlockTimesteps(stars, 0.1, 6, criterion, 0.5, WithUnits(NBodyUnits))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println(b.Levels())
+	// Output:
+	// [0 6 5 3]
+}
+
+func TestTimestepCriterion(t *testing.T) {
+	tests := []struct {
+		name         string
+		criterion    TimestepCriterion
+		acceleration Vec2
+		jerk         Vec2
+		want         float64
+	}{
+		{
+			name:         "power criterion",
+			criterion:    PowerCriterion{Eta: 0.2, Softening: 0.5},
+			acceleration: Vec2{3, 4},
+			want:         0.2 * math.Sqrt(0.1),
+		},
+		{
+			name:         "power criterion without acceleration",
+			criterion:    PowerCriterion{Eta: 0.2, Softening: 0.5},
+			acceleration: Vec2{0, 0},
+			want:         math.Inf(1),
+		},
+		{
+			name:         "aarseth criterion",
+			criterion:    AarsethCriterion{Eta: 0.02},
+			acceleration: Vec2{3, 4},
+			jerk:         Vec2{0, 10},
+			want:         0.01,
+		},
+		{
+			name:         "aarseth criterion without jerk",
+			criterion:    AarsethCriterion{Eta: 0.02},
+			acceleration: Vec2{3, 4},
+			want:         0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.criterion.Timestep(Star2D{}, tt.acceleration, tt.jerk)
+			if got != tt.want && math.Abs(got-tt.want) > 1e-12 {
+				t.Errorf("Timestep() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlockTimesteps_singleLevel(t *testing.T) {
+	stars := randomStars(100, 10, 24)
+	for i := range stars {
+		stars[i].V = stars[i].V.Multiply(0.01)
+		stars[i].M /= 1000
+	}
+	opts := []ForceOption{WithUnits(NBodyUnits), WithSoftening(PlummerSoftening, 0.1)}
+
+	// all the stars use the largest timestep, so the result is the same as using a leapfrog
+	b, err := NewBlockTimesteps(stars, 0.05, 4, fixedTimestep(1), 0, opts...)
+	if err != nil {
+		t.Fatalf("NewBlockTimesteps() error = %v", err)
+	}
+
+	want := append([]Star2D(nil), stars...)
+	integrator := &Leapfrog{}
+	for i := 0; i < 20; i++ {
+		if err := b.Step(); err != nil {
+			t.Fatalf("BlockTimesteps.Step() error = %v", err)
+		}
+		if err := integrator.Step(want, float64(i)*0.05, 0.05, DirectAccelerations{Options: opts}); err != nil {
+			t.Fatalf("Leapfrog.Step() error = %v", err)
+		}
+	}
+
+	got := b.Stars()
+	for i := range got {
+		if math.Hypot(got[i].C.X-want[i].C.X, got[i].C.Y-want[i].C.Y) > 1e-9 ||
+			math.Hypot(got[i].V.X-want[i].V.X, got[i].V.Y-want[i].V.Y) > 1e-9 {
+			t.Errorf("BlockTimesteps.Step() = %v, want %v", got[i], want[i])
+		}
+	}
+	if b.Time() != 20*0.05 && math.Abs(b.Time()-1) > 1e-12 {
+		t.Errorf("BlockTimesteps.Time() = %v, want %v", b.Time(), 1)
+	}
+
+	// the tree stores the advanced stars
+	if got, want := len(b.Root().GetAllStars()), len(stars); got != want {
+		t.Errorf("BlockTimesteps.Root() stores %v stars, want %v", got, want)
+	}
+	for _, star := range got {
+		if b.Root().Remove(star) == false {
+			t.Errorf("BlockTimesteps.Root() does not store %v", star)
+		}
+	}
+}
+
+func TestBlockTimesteps_kepler(t *testing.T) {
+	radii := []float64{0.25, 0.5, 1, 2, 4}
+
+	tests := []struct {
+		name      string
+		criterion TimestepCriterion
+	}{
+		{
+			name:      "power criterion",
+			criterion: PowerCriterion{Eta: 0.05, Softening: 0.01},
+		},
+		{
+			name:      "aarseth criterion",
+			criterion: AarsethCriterion{Eta: 0.01},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := NewBlockTimesteps(keplerStars(radii), 0.1, 8, tt.criterion, 0.5, WithUnits(NBodyUnits))
+			if err != nil {
+				t.Fatalf("NewBlockTimesteps() error = %v", err)
+			}
+
+			// advance the stars for more than a whole orbit of the outermost star
+			for i := 0; i < 600; i++ {
+				if err := b.Step(); err != nil {
+					t.Fatalf("BlockTimesteps.Step() error = %v", err)
+				}
+			}
+
+			// the stars still move on circular orbits
+			stars := b.Stars()
+			for i, radius := range radii {
+				star := stars[i+1]
+				if got := math.Hypot(star.C.X, star.C.Y); math.Abs(got-radius) > 1e-2*radius {
+					t.Errorf("BlockTimesteps.Step() radius = %v, want %v", got, radius)
+				}
+			}
+
+			// stars closer to the center use smaller timesteps
+			levels := b.Levels()
+			for i := 2; i < len(levels); i++ {
+				if levels[i] > levels[i-1] {
+					t.Errorf("BlockTimesteps.Levels() = %v, want the levels to decrease with the radius", levels)
+				}
+			}
+			if levels[1] == levels[len(levels)-1] {
+				t.Errorf("BlockTimesteps.Levels() = %v, want different levels", levels)
+			}
+		})
+	}
+}
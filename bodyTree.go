@@ -0,0 +1,52 @@
+// bodyTree.go defines the bodies that can be stored in the tree
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+// Body is anything that can be stored in a tree, such as stars, gas particles or dark matter particles. Bodies are
+// compared using ==, so two bodies are the same body if all their fields are equal.
+type Body interface {
+	comparable
+
+	// Position returns the position of the body
+	Position() Vec2
+
+	// Mass returns the mass of the body
+	Mass() float64
+}
+
+// NewBodyRoot returns a pointer to a root node of a tree storing bodies of the type B. The BoundingBox of the root
+// is centered at the origin and has the given width, the tree grown from it uses the given configuration.
+func NewBodyRoot[B Body](BoundingBoxWidth float64, config TreeConfig) *BodyNode[B] {
+	return &BodyNode[B]{
+		Boundary: NewBoundingBox(Vec2{0, 0}, BoundingBoxWidth),
+		Config:   config,
+	}
+}
+
+// NewBodyNode creates a new node using the given bounding box
+func NewBodyNode[B Body](boundary BoundingBox) *BodyNode[B] {
+	return &BodyNode[B]{Boundary: boundary}
+}
+
+// bodyStar returns the star the forces acting on the body are calculated for. Stars are used as they are, other
+// bodies are replaced by a star at their position using their mass.
+func bodyStar[B Body](body B) Star2D {
+	if star, ok := any(body).(Star2D); ok {
+		return star
+	}
+	return Star2D{C: body.Position(), M: body.Mass()}
+}
@@ -0,0 +1,263 @@
+// bodyTree_test.go provides tests for bodyTree.go
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// gasParticle is a body that isn't a star
+type gasParticle struct {
+	position    Vec2
+	mass        float64
+	temperature float64
+}
+
+func (p gasParticle) Position() Vec2 {
+	return p.position
+}
+
+func (p gasParticle) Mass() float64 {
+	return p.mass
+}
+
+// A BodyNode stores the stars of multiple galaxies without losing the galaxy they come from
+func ExampleBodyNode() {
+	root := NewBodyRoot[Stargalaxy](100, TreeConfig{})
+	_ = root.Insert(Stargalaxy{Star: NewStar2D(Vec2{10, 20}, Vec2{0, 0}, 10), Index: 0})
+	_ = root.Insert(Stargalaxy{Star: NewStar2D(Vec2{-10, -20}, Vec2{0, 0}, 10), Index: 1})
+
+	for _, body := range root.GetAllBodies() {
+		fmt.Println(body.Index, body.Position())
+	}
+	fmt.Println(root.Len(), root.TotalMass)
+	// Output:
+	// 0 {10 20}
+	// 1 {-10 -20}
+	// 2 20
+}
+
+func TestBodyNode_Insert(t *testing.T) {
+
+	// a star at the origin without mass and velocity is stored like any other star
+	root := NewRoot(100)
+	if err := root.Insert(Star2D{}); err != nil || root.Len() != 1 {
+		t.Errorf("Node.Insert() error = %v, stored %v stars, want 1", err, root.Len())
+	}
+
+	tests := []struct {
+		name   string
+		bodies []gasParticle
+	}{
+		{
+			name:   "massless particle at the origin",
+			bodies: []gasParticle{{}},
+		},
+		{
+			name:   "massless particle next to others",
+			bodies: []gasParticle{{position: Vec2{1, 1}, mass: 2}, {}, {position: Vec2{-1, -1}, mass: 2, temperature: 100}},
+		},
+		{
+			name:   "particles on the center lines",
+			bodies: []gasParticle{{}, {position: Vec2{0, 10}, mass: 1}, {position: Vec2{-10, 0}, mass: 1}, {position: Vec2{0, -10}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := NewBodyRoot[gasParticle](100, TreeConfig{})
+			totalMass := 0.0
+			for _, body := range tt.bodies {
+				if err := root.Insert(body); err != nil {
+					t.Fatalf("BodyNode.Insert() error = %v", err)
+				}
+				totalMass += body.mass
+			}
+
+			// every body is stored, including the massless ones
+			if root.Len() != len(tt.bodies) || root.TotalMass != totalMass {
+				t.Errorf("BodyNode.Insert() stored %v bodies of mass %v, want %v of mass %v", root.Len(), root.TotalMass, len(tt.bodies), totalMass)
+			}
+			bodies := root.GetAllBodies()
+			for _, body := range tt.bodies {
+				found := false
+				for _, stored := range bodies {
+					found = found || stored == body
+				}
+				if found == false {
+					t.Errorf("BodyNode.GetAllBodies() = %v, want it to contain %v", bodies, body)
+				}
+			}
+		})
+	}
+}
+
+func TestBodyNode_Insert_error(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  TreeConfig
+		body    gasParticle
+		wantErr error
+	}{
+		{
+			name:    "outside of the boundary",
+			body:    gasParticle{position: Vec2{60, 0}},
+			wantErr: ErrOutOfBounds,
+		},
+		{
+			name:    "duplicate position",
+			body:    gasParticle{position: Vec2{10, 20}, mass: 3},
+			wantErr: ErrDuplicatePosition,
+		},
+		{
+			name:    "maximum depth exceeded",
+			config:  NewTreeConfig(2, 0),
+			body:    gasParticle{position: Vec2{11, 21}},
+			wantErr: ErrMaxDepthExceeded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := NewBodyRoot[gasParticle](100, tt.config)
+			_ = root.Insert(gasParticle{position: Vec2{10, 20}, mass: 1})
+			_ = root.Insert(gasParticle{position: Vec2{-10, -20}, mass: 1})
+			before := root.GenForestTree(root)
+
+			if err := root.Insert(tt.body); errors.Is(err, tt.wantErr) == false {
+				t.Errorf("BodyNode.Insert() error = %v, want %v", err, tt.wantErr)
+			}
+
+			// the tree is left unchanged
+			if after := root.GenForestTree(root); after != before || root.TotalMass != 2 || root.Len() != 2 {
+				t.Errorf("BodyNode.Insert() changed the tree to %v, want %v", after, before)
+			}
+		})
+	}
+}
+
+func TestBodyNode_Node(t *testing.T) {
+	stars := randomStars(300, 100, 41)
+	particles := make([]gasParticle, len(stars))
+	for i, star := range stars {
+		particles[i] = gasParticle{position: star.C, mass: star.M}
+	}
+
+	tests := []struct {
+		name   string
+		config TreeConfig
+		theta  float64
+		opts   []ForceOption
+	}{
+		{name: "exact", theta: 0},
+		{name: "classic criterion", theta: 0.5},
+		{name: "buckets and quadrupole", config: NewTreeConfig(4, 3), theta: 0.7, opts: []ForceOption{WithQuadrupole()}},
+		{name: "softened bmax criterion", theta: 0.5, opts: []ForceOption{WithSoftening(SplineSoftening, 1), WithOpeningCriterion(BmaxCriterion)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := NewRootWithConfig(100, tt.config)
+			bodyRoot := NewBodyRoot[gasParticle](100, tt.config)
+			for i, star := range stars {
+				if err := root.Insert(star); err != nil {
+					t.Fatalf("Node.Insert() error = %v", err)
+				}
+				if err := bodyRoot.Insert(particles[i]); err != nil {
+					t.Fatalf("BodyNode.Insert() error = %v", err)
+				}
+			}
+
+			// a tree storing gas particles is built the same way a tree storing stars at their positions is
+			if got, want := bodyRoot.GenForestTree(bodyRoot), root.GenForestTree(root); got != want {
+				t.Errorf("BodyNode.GenForestTree() = %v, want %v", got, want)
+			}
+			if bodyRoot.TotalMass != root.TotalMass || bodyRoot.CenterOfMass != root.CenterOfMass || bodyRoot.Quadrupole != root.Quadrupole {
+				t.Errorf("BodyNode moments = (%v, %v, %v), want (%v, %v, %v)", bodyRoot.TotalMass, bodyRoot.CenterOfMass,
+					bodyRoot.Quadrupole, root.TotalMass, root.CenterOfMass, root.Quadrupole)
+			}
+
+			// the forces are the same, too
+			for i, star := range stars[:50] {
+				if got, want := bodyRoot.CalcAllForces(particles[i], tt.theta, tt.opts...), root.CalcAllForces(star, tt.theta, tt.opts...); got != want {
+					t.Errorf("BodyNode.CalcAllForces() = %v, want %v", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestBodyNode_Remove(t *testing.T) {
+	stars := randomStars(200, 100, 42)
+	stars = append(stars, Star2D{})
+
+	root := NewBodyRoot[Star2D](100, NewTreeConfig(0, 2))
+	for _, star := range stars {
+		if err := root.Insert(star); err != nil {
+			t.Fatalf("BodyNode.Insert() error = %v", err)
+		}
+	}
+
+	if root.Remove(NewStar2D(Vec2{10, 10}, Vec2{0, 0}, 1)) == true {
+		t.Errorf("BodyNode.Remove() found a body that isn't in the tree")
+	}
+
+	random := rand.New(rand.NewSource(43))
+	for i, index := range random.Perm(len(stars)) {
+		if root.Remove(stars[index]) == false {
+			t.Fatalf("BodyNode.Remove() = false, want %v to be found", stars[index])
+		}
+		if root.Len() != len(stars)-i-1 {
+			t.Fatalf("BodyNode.Len() = %v, want %v", root.Len(), len(stars)-i-1)
+		}
+	}
+
+	// the tree collapsed back into an empty root
+	if root.Subtrees != ([4]*BodyNode[Star2D]{}) || len(root.Bodies) != 0 || root.TotalMass != 0 || root.Quadrupole != (Quadrupole{}) {
+		t.Errorf("BodyNode.Remove() left %v behind", root.GenForestTree(root))
+	}
+}
+
+func TestBodyNode_Update(t *testing.T) {
+	root := NewBodyRoot[gasParticle](100, TreeConfig{AutoExpand: true})
+	particles := []gasParticle{{position: Vec2{10, 20}, mass: 1}, {}, {position: Vec2{-10, -20}, mass: 2, temperature: 50}}
+	for _, particle := range particles {
+		if err := root.Insert(particle); err != nil {
+			t.Fatalf("BodyNode.Insert() error = %v", err)
+		}
+	}
+
+	// the particle heats up and leaves the root, so the root grows towards it
+	moved := gasParticle{position: Vec2{300, -20}, mass: 2, temperature: 80}
+	if err := root.Update(particles[2], moved); err != nil {
+		t.Fatalf("BodyNode.Update() error = %v", err)
+	}
+	if root.Len() != 3 || root.contains(moved) == false || root.contains(particles[2]) == true {
+		t.Errorf("BodyNode.Update() left %v", root.GetAllBodies())
+	}
+	if root.Boundary.Contains(moved.position) == false {
+		t.Errorf("BodyNode.Update() left the particle outside of %v", root.Boundary)
+	}
+
+	// the massless particle at the origin is found and moved like any other particle
+	if err := root.Update(particles[1], gasParticle{position: Vec2{1, 1}, mass: 1}); err != nil {
+		t.Fatalf("BodyNode.Update() error = %v", err)
+	}
+	if want := (Vec2{(10 + 600 + 1) / 4.0, (20 - 40 + 1) / 4.0}); root.TotalMass != 4 || root.CenterOfMass.Sub(want).Len() > 1e-12 {
+		t.Errorf("BodyNode.Update() moments = (%v, %v), want (4, %v)", root.TotalMass, root.CenterOfMass, want)
+	}
+}
@@ -16,6 +16,8 @@
 
 package structs
 
+import "math"
+
 // BoundingBox is a struct defining the spatial outreach of a box
 type BoundingBox struct {
 	Center Vec2    // Center of the box
@@ -26,3 +28,76 @@ type BoundingBox struct {
 func NewBoundingBox(center Vec2, width float64) BoundingBox {
 	return BoundingBox{Center: center, Width: width}
 }
+
+// maxFittingSteps is the maximum amount of steps a fitting box is widened by to make up for rounding errors
+const maxFittingSteps = 64
+
+// NewBoundingBoxFitting returns the smallest Bounding Box containing all of the given bodies. If all of the bodies
+// are at the same position, the box has a width of 1. Bodies whose coordinates aren't finite can't be contained in
+// any box, so they are left out.
+func NewBoundingBoxFitting[B Body](stars []B) BoundingBox {
+	var finite []Vec2
+	for _, star := range stars {
+		if position := star.Position(); position.IsFinite() {
+			finite = append(finite, position)
+		}
+	}
+	if len(finite) == 0 {
+		return BoundingBox{Width: 1}
+	}
+
+	min := finite[0]
+	max := finite[0]
+	for _, position := range finite[1:] {
+		min.X = math.Min(min.X, position.X)
+		min.Y = math.Min(min.Y, position.Y)
+		max.X = math.Max(max.X, position.X)
+		max.Y = math.Max(max.Y, position.Y)
+	}
+
+	width := math.Max(max.X-min.X, max.Y-min.Y)
+	if width == 0 {
+		width = 1
+	}
+
+	box := BoundingBox{
+		Center: Vec2{(min.X + max.X) / 2, (min.Y + max.Y) / 2},
+		Width:  width,
+	}
+
+	// rounding errors can leave the outermost stars just outside of the box, so it is widened until they fit
+	for i := 0; i < maxFittingSteps && (box.Contains(min) == false || box.Contains(max) == false); i++ {
+		box.Width = math.Nextafter(box.Width, math.Inf(1))
+	}
+
+	return box
+}
+
+// Contains returns true if the given point is inside of the bounding box or on its edge
+func (b BoundingBox) Contains(point Vec2) bool {
+	halfWidth := b.Width / 2
+	return point.X >= b.Center.X-halfWidth && point.X <= b.Center.X+halfWidth &&
+		point.Y >= b.Center.Y-halfWidth && point.Y <= b.Center.Y+halfWidth
+}
+
+// Quadrant returns the bounding box of the given quadrant (NW, NE, SW, SE) of the bounding box
+func (b BoundingBox) Quadrant(quadrant int) BoundingBox {
+
+	// define new values defining the new BoundaryBoxes
+	newBoundaryWidth := b.Width / 2
+	newBoundaryPosX := b.Center.X + (newBoundaryWidth / 2)
+	newBoundaryPosY := b.Center.Y + (newBoundaryWidth / 2)
+	newBoundaryNegX := b.Center.X - (newBoundaryWidth / 2)
+	newBoundaryNegY := b.Center.Y - (newBoundaryWidth / 2)
+
+	switch quadrant {
+	case 0:
+		return BoundingBox{Vec2{newBoundaryNegX, newBoundaryPosY}, newBoundaryWidth}
+	case 1:
+		return BoundingBox{Vec2{newBoundaryPosX, newBoundaryPosY}, newBoundaryWidth}
+	case 2:
+		return BoundingBox{Vec2{newBoundaryNegX, newBoundaryNegY}, newBoundaryWidth}
+	default:
+		return BoundingBox{Vec2{newBoundaryPosX, newBoundaryNegY}, newBoundaryWidth}
+	}
+}
@@ -0,0 +1,94 @@
+// boundingBox3D.go defines the boundary of a node in the octree
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+import "math"
+
+// BoundingBox3D is a struct defining the spatial outreach of a cube
+type BoundingBox3D struct {
+	Center Vec3    // Center of the cube
+	Width  float64 // Width of the cube
+}
+
+// NewBoundingBox3D returns a new cube using the centerpoint and the width given by the function parameters
+func NewBoundingBox3D(center Vec3, width float64) BoundingBox3D {
+	return BoundingBox3D{Center: center, Width: width}
+}
+
+// NewBoundingBox3DFitting returns the smallest cube containing all of the given stars. If all of the stars are at
+// the same position, the cube has a width of 1. Stars whose coordinates aren't finite can't be contained in any
+// cube, so they are left out.
+func NewBoundingBox3DFitting(stars []Star3D) BoundingBox3D {
+	var finite []Star3D
+	for _, star := range stars {
+		if star.C.IsFinite() {
+			finite = append(finite, star)
+		}
+	}
+	if len(finite) == 0 {
+		return BoundingBox3D{Width: 1}
+	}
+
+	min := finite[0].C
+	max := finite[0].C
+	for _, star := range finite[1:] {
+		min = Vec3{math.Min(min.X, star.C.X), math.Min(min.Y, star.C.Y), math.Min(min.Z, star.C.Z)}
+		max = Vec3{math.Max(max.X, star.C.X), math.Max(max.Y, star.C.Y), math.Max(max.Z, star.C.Z)}
+	}
+
+	width := math.Max(max.X-min.X, math.Max(max.Y-min.Y, max.Z-min.Z))
+	if width == 0 {
+		width = 1
+	}
+
+	box := BoundingBox3D{
+		Center: min.Add(max).Multiply(0.5),
+		Width:  width,
+	}
+
+	// rounding errors can leave the outermost stars just outside of the cube, so it is widened until they fit
+	for i := 0; i < maxFittingSteps && (box.Contains(min) == false || box.Contains(max) == false); i++ {
+		box.Width = math.Nextafter(box.Width, math.Inf(1))
+	}
+
+	return box
+}
+
+// Contains returns true if the given point is inside of the cube or on its surface
+func (b BoundingBox3D) Contains(point Vec3) bool {
+	halfWidth := b.Width / 2
+	return point.X >= b.Center.X-halfWidth && point.X <= b.Center.X+halfWidth &&
+		point.Y >= b.Center.Y-halfWidth && point.Y <= b.Center.Y+halfWidth &&
+		point.Z >= b.Center.Z-halfWidth && point.Z <= b.Center.Z+halfWidth
+}
+
+// Octant returns the cube of the given octant of the cube. The octants 0 to 3 are the quadrants (NW, NE, SW, SE)
+// above the center, the octants 4 to 7 the ones below it.
+func (b BoundingBox3D) Octant(octant int) BoundingBox3D {
+	quarterWidth := b.Width / 4
+	center := Vec3{b.Center.X - quarterWidth, b.Center.Y + quarterWidth, b.Center.Z + quarterWidth}
+	if octant&1 != 0 {
+		center.X = b.Center.X + quarterWidth
+	}
+	if octant&2 != 0 {
+		center.Y = b.Center.Y - quarterWidth
+	}
+	if octant&4 != 0 {
+		center.Z = b.Center.Z - quarterWidth
+	}
+	return BoundingBox3D{center, b.Width / 2}
+}
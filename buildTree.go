@@ -0,0 +1,262 @@
+// buildTree.go builds whole trees from a list of stars at once
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// mortonLevels is the amount of tree levels encoded in a morton key
+const mortonLevels = 32
+
+// parallelBuildThreshold is the minimum amount of stars a subtree must contain to be built in its own goroutine
+const parallelBuildThreshold = 1024
+
+// BuildOption configures how BuildTree builds a tree
+type BuildOption func(*buildOptions)
+
+// buildOptions stores the options given to BuildTree
+type buildOptions struct {
+	config   TreeConfig
+	boundary *BoundingBox
+	workers  int
+}
+
+// WithTreeConfig builds the tree using the given configuration
+func WithTreeConfig(config TreeConfig) BuildOption {
+	return func(options *buildOptions) {
+		options.config = config
+	}
+}
+
+// WithBoundary builds the tree inside of the given bounding box instead of fitting the bounding box to the stars
+func WithBoundary(boundary BoundingBox) BuildOption {
+	return func(options *buildOptions) {
+		options.boundary = &boundary
+	}
+}
+
+// WithWorkers limits the amount of goroutines building subtrees at the same time. By default, as many goroutines
+// as there are CPUs available are used.
+func WithWorkers(workers int) BuildOption {
+	return func(options *buildOptions) {
+		options.workers = workers
+	}
+}
+
+// mortonBody bundles a body with its index in the list of bodies and its morton key
+type mortonBody[B Body] struct {
+	body  B
+	index int
+	key   uint64
+}
+
+// BuildTree builds a tree containing all the given bodies. The bodies are sorted by their morton key, so the bodies
+// of every quadrant are next to each other, and the subtrees are built concurrently.
+// The resulting tree is identical to the tree built by inserting the bodies one after another into a root with the
+// same boundary. If one of the bodies can't be inserted, the error Insert would return is returned.
+func BuildTree[B Body](stars []B, opts ...BuildOption) (*BodyNode[B], error) {
+	options := buildOptions{workers: runtime.GOMAXPROCS(0)}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.workers < 1 {
+		options.workers = 1
+	}
+
+	// stars whose coordinates aren't finite can't be inside of any boundary
+	for _, star := range stars {
+		if position := star.Position(); position.IsFinite() == false {
+			return nil, fmt.Errorf("could not insert star (%f, %f): %w", position.X, position.Y, ErrOutOfBounds)
+		}
+	}
+
+	root := NewRootFitting(stars, options.config)
+	if options.boundary != nil {
+		root.Boundary = *options.boundary
+	}
+
+	// check all the stars the way Insert would check them before building anything
+	positions := make(map[Vec2]bool, len(stars))
+	for _, star := range stars {
+		position := star.Position()
+		if root.Boundary.Contains(position) == false {
+			if err := root.expandTowards(position); err != nil {
+				return nil, fmt.Errorf("could not insert star (%f, %f): %w", position.X, position.Y, err)
+			}
+		}
+		if positions[position] == true {
+			return nil, fmt.Errorf("could not insert star (%f, %f): %w", position.X, position.Y, ErrDuplicatePosition)
+		}
+		positions[position] = true
+	}
+
+	// calculate the morton keys of all the stars concurrently and sort the stars using them
+	items := make([]mortonBody[B], len(stars))
+	chunkSize := (len(stars) + options.workers - 1) / options.workers
+	var wg sync.WaitGroup
+	for start := 0; start < len(stars); start += chunkSize {
+		end := start + chunkSize
+		if end > len(stars) {
+			end = len(stars)
+		}
+
+		wg.Add(1)
+		go func(start int, end int) {
+			defer wg.Done()
+			for i := start; i < end; i++ {
+				items[i] = mortonBody[B]{body: stars[i], index: i, key: mortonKey(stars[i].Position(), root.Boundary)}
+			}
+		}(start, end)
+	}
+	wg.Wait()
+
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].key == items[j].key {
+			return items[i].index < items[j].index
+		}
+		return items[i].key < items[j].key
+	})
+
+	builder := treeBuilder[B]{tokens: make(chan struct{}, options.workers-1)}
+	if err := builder.build(root, items, 0); err != nil {
+		return nil, err
+	}
+
+	return root, nil
+}
+
+// mortonKey returns the morton key of the given position inside of the boundary. Every two bits of the key store
+// the quadrant the position is in on one level of the tree, starting with the root in the most significant bits.
+func mortonKey(position Vec2, boundary BoundingBox) uint64 {
+	star := Star2D{C: position}
+
+	var key uint64
+	for level := 0; level < mortonLevels; level++ {
+		quadrant := star.getRelativePositionInt(boundary)
+		key = key<<2 | uint64(quadrant)
+		boundary = boundary.Quadrant(quadrant)
+	}
+
+	return key
+}
+
+// treeBuilder builds subtrees from sorted bodies using a limited amount of goroutines
+type treeBuilder[B Body] struct {
+	tokens chan struct{}
+}
+
+// build builds the subtree below the given node out of the given stars sorted by their morton keys. The level is
+// the depth of the node relative to the root the morton keys were calculated for.
+func (b *treeBuilder[B]) build(node *BodyNode[B], items []mortonBody[B], level int) error {
+
+	// the stars fit into the node, so it becomes a leaf
+	if len(items) <= node.Config.capacity() || node.Depth >= node.Config.maxDepth() {
+
+		// the stars are stored in the order they would have been inserted in
+		sort.Slice(items, func(i, j int) bool {
+			return items[i].index < items[j].index
+		})
+
+		if len(items) > node.Config.capacity() && node.Config.buckets() == false {
+			position := items[node.Config.capacity()].body.Position()
+			return fmt.Errorf("could not insert star (%f, %f): %w", position.X, position.Y, ErrMaxDepthExceeded)
+		}
+
+		var bodies []B
+		for _, item := range items {
+			bodies = append(bodies, item.body)
+		}
+		node.Bodies = bodies
+		node.updateMoments()
+		return nil
+	}
+
+	node.Subdivide()
+
+	// split the stars into the quadrants of the node
+	var quadrants [4][]mortonBody[B]
+	if level < mortonLevels {
+
+		// the stars are sorted by their morton key, so the stars of a quadrant are next to each other
+		shift := uint(2 * (mortonLevels - 1 - level))
+		start := 0
+		for quadrant := range quadrants {
+			end := start + sort.Search(len(items)-start, func(i int) bool {
+				return int(items[start+i].key>>shift&3) > quadrant
+			})
+			quadrants[quadrant] = items[start:end]
+			start = end
+		}
+	} else {
+
+		// the morton keys don't reach this deep, so the quadrants are calculated directly
+		for _, item := range items {
+			quadrant := node.quadrantOf(item.body.Position())
+			quadrants[quadrant] = append(quadrants[quadrant], item)
+		}
+	}
+
+	// build the subtrees, using a new goroutine for big subtrees as long as there are workers left
+	var errs [4]error
+	var wg sync.WaitGroup
+	for quadrant := range quadrants {
+		if len(quadrants[quadrant]) == 0 {
+			continue
+		}
+
+		if len(quadrants[quadrant]) >= parallelBuildThreshold && b.acquire() {
+			wg.Add(1)
+			go func(quadrant int) {
+				defer wg.Done()
+				defer b.release()
+				errs[quadrant] = b.build(node.Subtrees[quadrant], quadrants[quadrant], level+1)
+			}(quadrant)
+			continue
+		}
+
+		errs[quadrant] = b.build(node.Subtrees[quadrant], quadrants[quadrant], level+1)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	node.updateMoments()
+	return nil
+}
+
+// acquire tries to reserve a worker and returns true if it succeeded
+func (b *treeBuilder[B]) acquire() bool {
+	select {
+	case b.tokens <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// release frees a worker reserved using acquire
+func (b *treeBuilder[B]) release() {
+	<-b.tokens
+}
@@ -0,0 +1,200 @@
+// buildTree_test.go provides tests for buildTree.go
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// randomStars returns n stars at random positions inside of a box of the given width centered at (0, 0)
+func randomStars(n int, width float64, seed int64) []Star2D {
+	random := rand.New(rand.NewSource(seed))
+
+	stars := make([]Star2D, n)
+	for i := range stars {
+		stars[i] = NewStar2D(
+			Vec2{(random.Float64() - 0.5) * width, (random.Float64() - 0.5) * width},
+			Vec2{random.Float64(), random.Float64()},
+			random.Float64()*10+1,
+		)
+	}
+	return stars
+}
+
+// BuildTree builds a whole tree at once.
+// The tree below is the same tree that would have been built by inserting the stars one after another.
+func ExampleBuildTree() {
+	stars := []Star2D{
+		NewStar2D(Vec2{10, 20}, Vec2{0, 0}, 10),
+		NewStar2D(Vec2{-10, -20}, Vec2{0, 0}, 10),
+		NewStar2D(Vec2{12, 22}, Vec2{0, 0}, 10),
+	}
+
+	root, err := BuildTree(stars, WithBoundary(NewBoundingBox(Vec2{0, 0}, 100)))
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(root.GenForestTree(root))
+	// Output:
+	// [[[][][][]][[[][][][]][[][][][]][[[[][][][]][[[][][][]][12 22[][][][]][[][][][]][10 20[][][][]]][[][][][]][[][][][]]][[][][][]][[][][][]][[][][][]]][[][][][]]][-10 -20[][][][]][[][][][]]]
+}
+
+func TestBuildTree(t *testing.T) {
+	tests := []struct {
+		name  string
+		stars []Star2D
+		opts  []BuildOption
+	}{
+		{
+			name:  "No stars",
+			stars: []Star2D{},
+		},
+		{
+			name:  "A few stars",
+			stars: randomStars(10, 100, 1),
+		},
+		{
+			name:  "A star at the origin without mass",
+			stars: append(randomStars(10, 100, 1), Star2D{}),
+		},
+		{
+			name:  "Many stars built concurrently",
+			stars: randomStars(20000, 1e6, 2),
+			opts:  []BuildOption{WithWorkers(8)},
+		},
+		{
+			name:  "Many stars built by a single worker",
+			stars: randomStars(5000, 1e6, 3),
+			opts:  []BuildOption{WithWorkers(1)},
+		},
+		{
+			name:  "Leaves holding multiple stars",
+			stars: randomStars(5000, 100, 4),
+			opts:  []BuildOption{WithTreeConfig(NewTreeConfig(0, 8))},
+		},
+		{
+			name:  "Buckets at the maximum depth",
+			stars: randomStars(5000, 100, 5),
+			opts:  []BuildOption{WithTreeConfig(NewTreeConfig(3, 1))},
+		},
+		{
+			name:  "Buckets deeper than the morton keys reach",
+			stars: randomStars(2000, 1e-6, 6),
+			opts: []BuildOption{
+				WithTreeConfig(NewTreeConfig(40, 1)),
+				WithBoundary(NewBoundingBox(Vec2{0, 0}, 1e4)),
+			},
+		},
+		{
+			name:  "Given boundary",
+			stars: randomStars(1000, 100, 7),
+			opts:  []BuildOption{WithBoundary(NewBoundingBox(Vec2{10, -10}, 200))},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := BuildTree(tt.stars, tt.opts...)
+			if err != nil {
+				t.Fatalf("BuildTree() error = %v", err)
+			}
+
+			// build the same tree by inserting the stars one after another
+			options := buildOptions{}
+			for _, opt := range tt.opts {
+				opt(&options)
+			}
+			want := NewRootFitting(tt.stars, options.config)
+			if options.boundary != nil {
+				want.Boundary = *options.boundary
+			}
+			for _, star := range tt.stars {
+				if err := want.Insert(star); err != nil {
+					t.Fatalf("Node.Insert() error = %v", err)
+				}
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("BuildTree() differs from a tree built using Node.Insert()")
+			}
+		})
+	}
+}
+
+func TestBuildTree_error(t *testing.T) {
+	star := NewStar2D(Vec2{10, 20}, Vec2{0, 0}, 10)
+
+	tests := []struct {
+		name    string
+		stars   []Star2D
+		opts    []BuildOption
+		wantErr error
+	}{
+		{
+			name:    "Two stars at the same position",
+			stars:   []Star2D{star, NewStar2D(Vec2{10, 20}, Vec2{1, 1}, 20)},
+			wantErr: ErrDuplicatePosition,
+		},
+		{
+			name:    "A star outside of the given boundary",
+			stars:   []Star2D{star, NewStar2D(Vec2{-100, 20}, Vec2{1, 1}, 20)},
+			opts:    []BuildOption{WithBoundary(NewBoundingBox(Vec2{0, 0}, 100))},
+			wantErr: ErrOutOfBounds,
+		},
+		{
+			name:    "A star at a position that isn't finite",
+			stars:   []Star2D{star, NewStar2D(Vec2{math.NaN(), 20}, Vec2{1, 1}, 20)},
+			wantErr: ErrOutOfBounds,
+		},
+		{
+			name:    "A star at an infinite position in a growing tree",
+			stars:   []Star2D{star, NewStar2D(Vec2{10, math.Inf(-1)}, Vec2{1, 1}, 20)},
+			opts:    []BuildOption{WithTreeConfig(TreeConfig{AutoExpand: true})},
+			wantErr: ErrOutOfBounds,
+		},
+		{
+			name:  "A star outside of the given boundary of a growing tree",
+			stars: []Star2D{star, NewStar2D(Vec2{-100, 20}, Vec2{1, 1}, 20)},
+			opts: []BuildOption{
+				WithBoundary(NewBoundingBox(Vec2{0, 0}, 100)),
+				WithTreeConfig(TreeConfig{AutoExpand: true}),
+			},
+			wantErr: nil,
+		},
+		{
+			name:  "Stars too close to each other",
+			stars: []Star2D{star, NewStar2D(Vec2{10.001, 20.001}, Vec2{1, 1}, 20)},
+			opts: []BuildOption{
+				WithTreeConfig(NewTreeConfig(2, 0)),
+				WithBoundary(NewBoundingBox(Vec2{0, 0}, 100)),
+			},
+			wantErr: ErrMaxDepthExceeded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := BuildTree(tt.stars, tt.opts...); !errors.Is(err, tt.wantErr) {
+				t.Errorf("BuildTree() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
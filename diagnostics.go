@@ -0,0 +1,175 @@
+// diagnostics.go calculates conserved quantities used to check the health of a simulation
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+import (
+	"math"
+)
+
+// Diagnostics bundles the conserved quantities of a set of stars
+type Diagnostics struct {
+	KineticEnergy        float64 // Sum of m*v^2/2
+	PotentialEnergy      float64 // Potential energy W of all pairs of stars
+	TotalEnergy          float64 // KineticEnergy + PotentialEnergy
+	Momentum             Vec2    // Sum of m*v
+	AngularMomentum      float64 // z component of the sum of m * (r x v) around the origin
+	CenterOfMass         Vec2    // Center of mass of all the stars
+	CenterOfMassVelocity Vec2    // Velocity of the center of mass
+	VirialRatio          float64 // 2K/|W|, 1 for a system in virial equilibrium
+}
+
+// Diagnose calculates the conserved quantities of the given stars. If root is nil, the potential energy is
+// calculated by summing up all the pairs of stars directly using DirectSum, otherwise it is calculated by walking the
+// given tree, which has to contain the stars, using theta. The options define the softening and the unit system the
+// same way they do for the forces.
+func Diagnose(stars []Star2D, root *Node, theta float64, opts ...ForceOption) Diagnostics {
+	var d Diagnostics
+
+	// the potential energy of every pair is part of the potential energy of both of its stars, so it is halved
+	if root == nil {
+		_, potentials := DirectSum(stars, 0, opts...)
+		for i, star := range stars {
+			d.PotentialEnergy += star.M * potentials[i] / 2
+		}
+	} else {
+		for _, star := range stars {
+			d.PotentialEnergy += root.CalcPotential(star, theta, opts...) / 2
+		}
+	}
+
+	totalMass := 0.0
+	weightedPosition := Vec2{}
+	for _, star := range stars {
+		d.KineticEnergy += star.M * (star.V.X*star.V.X + star.V.Y*star.V.Y) / 2
+		d.Momentum = d.Momentum.Add(star.V.Multiply(star.M))
+		d.AngularMomentum += star.M * star.C.Cross(star.V)
+
+		totalMass += star.M
+		weightedPosition = weightedPosition.Add(star.C.Multiply(star.M))
+	}
+
+	d.TotalEnergy = d.KineticEnergy + d.PotentialEnergy
+	if totalMass != 0 {
+		d.CenterOfMass = weightedPosition.Multiply(1 / totalMass)
+		d.CenterOfMassVelocity = d.Momentum.Multiply(1 / totalMass)
+	}
+	if d.PotentialEnergy != 0 {
+		d.VirialRatio = 2 * d.KineticEnergy / math.Abs(d.PotentialEnergy)
+	}
+
+	return d
+}
+
+// Drift defines how far the conserved quantities drifted away from their initial values
+type Drift struct {
+	Energy          float64 // |E - E0| / |E0|
+	Momentum        float64 // |P - P0| / sum of m*|v| at the start
+	AngularMomentum float64 // |L - L0| / sum of m*|r x v| at the start
+	CenterOfMass    float64 // |R - R0 - V0*t| / mean distance of the stars from the center of mass at the start
+}
+
+// DiagnosticsRecord is a single entry of the time series recorded by a DiagnosticsRecorder
+type DiagnosticsRecord struct {
+	Time        float64
+	Diagnostics Diagnostics
+	Drift       Drift
+
+	// Warnings names the conserved quantities whose drift crossed the tolerance of the recorder with this record
+	Warnings []string
+}
+
+// DiagnosticsRecorder records the conserved quantities of a simulation over time and tracks how far they drift away
+// from the first recorded values
+type DiagnosticsRecorder struct {
+	Tolerance float64 // Largest tolerated relative drift of any of the conserved quantities
+
+	records       []DiagnosticsRecord
+	momentumScale float64
+	angularScale  float64
+	positionScale float64
+	exceeded      [4]bool // drifts of the energy, the momenta and the center of mass exceeding the tolerance
+}
+
+// NewDiagnosticsRecorder returns a new recorder using the given tolerance
+func NewDiagnosticsRecorder(tolerance float64) *DiagnosticsRecorder {
+	return &DiagnosticsRecorder{Tolerance: tolerance}
+}
+
+// Record diagnoses the given stars the same way Diagnose does and adds the result to the time series. The first
+// record defines the initial values the drifts are measured against.
+// If the drift of the energy, the momentum, the angular momentum or the center of mass crosses the tolerance, the
+// quantity is named in the Warnings of the returned record. The warning is only given once until the drift returns
+// below the tolerance.
+func (r *DiagnosticsRecorder) Record(time float64, stars []Star2D, root *Node, theta float64, opts ...ForceOption) DiagnosticsRecord {
+	d := Diagnose(stars, root, theta, opts...)
+
+	// the first record defines the initial values
+	if len(r.records) == 0 {
+		totalMass := 0.0
+		for _, star := range stars {
+			r.momentumScale += star.M * star.V.Len()
+			r.angularScale += star.M * math.Abs(star.C.Cross(star.V))
+			r.positionScale += star.M * star.C.Distance(d.CenterOfMass)
+			totalMass += star.M
+		}
+		if totalMass != 0 {
+			r.positionScale /= totalMass
+		}
+	}
+
+	record := DiagnosticsRecord{Time: time, Diagnostics: d}
+	if len(r.records) > 0 {
+		first := r.records[0]
+		initial := first.Diagnostics
+
+		record.Drift.Energy = relativeDrift(d.TotalEnergy-initial.TotalEnergy, initial.TotalEnergy)
+		record.Drift.Momentum = relativeDrift(d.Momentum.Sub(initial.Momentum).Len(), r.momentumScale)
+		record.Drift.AngularMomentum = relativeDrift(d.AngularMomentum-initial.AngularMomentum, r.angularScale)
+
+		// the center of mass moves with a constant velocity
+		expected := initial.CenterOfMass.Add(initial.CenterOfMassVelocity.Multiply(time - first.Time))
+		record.Drift.CenterOfMass = relativeDrift(d.CenterOfMass.Distance(expected), r.positionScale)
+	}
+
+	// check which drifts crossed the tolerance
+	names := [4]string{"energy", "momentum", "angular momentum", "center of mass"}
+	drifts := [4]float64{record.Drift.Energy, record.Drift.Momentum, record.Drift.AngularMomentum,
+		record.Drift.CenterOfMass}
+	for i, drift := range drifts {
+		exceeded := drift > r.Tolerance
+		if exceeded && r.exceeded[i] == false {
+			record.Warnings = append(record.Warnings, names[i])
+		}
+		r.exceeded[i] = exceeded
+	}
+
+	r.records = append(r.records, record)
+	return record
+}
+
+// Records returns a copy of all the recorded entries
+func (r *DiagnosticsRecorder) Records() []DiagnosticsRecord {
+	return append([]DiagnosticsRecord(nil), r.records...)
+}
+
+// relativeDrift returns |difference| / |scale| or |difference| if the scale is zero
+func relativeDrift(difference float64, scale float64) float64 {
+	if scale == 0 {
+		return math.Abs(difference)
+	}
+	return math.Abs(difference) / math.Abs(scale)
+}
@@ -0,0 +1,205 @@
+// diagnostics_test.go provides tests for diagnostics.go
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// Diagnose calculates the conserved quantities of the stars
+func ExampleDiagnose() {
+	stars := []Star2D{
+		NewStar2D(Vec2{-0.5, 0}, Vec2{0, -0.5}, 0.5),
+		NewStar2D(Vec2{0.5, 0}, Vec2{0, 0.5}, 0.5),
+	}
+
+	d := Diagnose(stars, nil, 0, WithUnits(NBodyUnits))
+	fmt.Printf("%.4f %.4f %.4f\n", d.KineticEnergy, d.PotentialEnergy, d.TotalEnergy)
+	fmt.Printf("%.4f %.4f %.4f\n", d.Momentum, d.AngularMomentum, d.VirialRatio)
+	// Output:
+	// 0.1250 -0.2500 -0.1250
+	// {0.0000 0.0000} 0.2500 1.0000
+}
+
+func TestDiagnose(t *testing.T) {
+	stars := randomStars(500, 100, 25)
+	opts := []ForceOption{WithUnits(NBodyUnits), WithSoftening(PlummerSoftening, 1)}
+
+	root, err := BuildTree(stars)
+	if err != nil {
+		t.Fatalf("BuildTree() error = %v", err)
+	}
+
+	// the potential energy is the sum over all pairs of stars
+	want := 0.0
+	for i := range stars {
+		for j := i + 1; j < len(stars); j++ {
+			want += CalcPotential(stars[i], stars[j], opts...)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		root      *Node
+		theta     float64
+		tolerance float64
+	}{
+		{
+			name:      "direct sum",
+			root:      nil,
+			tolerance: 1e-12,
+		},
+		{
+			name:      "tree opening every cell",
+			root:      root,
+			theta:     0,
+			tolerance: 1e-12,
+		},
+		{
+			name:      "tree",
+			root:      root,
+			theta:     0.5,
+			tolerance: 1e-2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := Diagnose(stars, tt.root, tt.theta, opts...)
+			if math.Abs(d.PotentialEnergy-want) > tt.tolerance*math.Abs(want) {
+				t.Errorf("Diagnose() PotentialEnergy = %v, want %v", d.PotentialEnergy, want)
+			}
+			if math.Abs(d.TotalEnergy-d.KineticEnergy-d.PotentialEnergy) > 1e-12*math.Abs(d.TotalEnergy) {
+				t.Errorf("Diagnose() TotalEnergy = %v, want %v", d.TotalEnergy, d.KineticEnergy+d.PotentialEnergy)
+			}
+			if math.Abs(d.VirialRatio-2*d.KineticEnergy/math.Abs(d.PotentialEnergy)) > 1e-12 {
+				t.Errorf("Diagnose() VirialRatio = %v, want %v", d.VirialRatio, 2*d.KineticEnergy/math.Abs(d.PotentialEnergy))
+			}
+		})
+	}
+
+	// stars without mass don't have any conserved quantities
+	if d := Diagnose([]Star2D{NewStar2D(Vec2{1, 1}, Vec2{1, 1}, 0)}, nil, 0); d != (Diagnostics{}) {
+		t.Errorf("Diagnose() = %v, want %v", d, Diagnostics{})
+	}
+}
+
+func TestDiagnosticsRecorder(t *testing.T) {
+	opts := []ForceOption{WithUnits(NBodyUnits)}
+	provider := DirectAccelerations{Options: opts}
+
+	tests := []struct {
+		name        string
+		integrator  Integrator
+		dt          float64
+		wantWarning bool
+	}{
+		{
+			name:        "leapfrog using a small timestep",
+			integrator:  &Leapfrog{},
+			dt:          0.001,
+			wantWarning: false,
+		},
+		{
+			name:        "euler using a large timestep",
+			integrator:  Euler{},
+			dt:          0.1,
+			wantWarning: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stars := []Star2D{
+				NewStar2D(Vec2{-0.5, 0}, Vec2{0.1, -0.4}, 0.5),
+				NewStar2D(Vec2{0.5, 0}, Vec2{0.1, 0.4}, 0.5),
+			}
+			recorder := NewDiagnosticsRecorder(1e-3)
+
+			warnings := 0
+			steps := int(math.Round(2 * math.Pi / tt.dt))
+			for i := 0; i <= steps; i++ {
+				if i > 0 {
+					if err := tt.integrator.Step(stars, float64(i-1)*tt.dt, tt.dt, provider); err != nil {
+						t.Fatalf("Integrator.Step() error = %v", err)
+					}
+				}
+
+				record := recorder.Record(float64(i)*tt.dt, stars, nil, 0, opts...)
+				warnings += len(record.Warnings)
+			}
+
+			if (warnings > 0) != tt.wantWarning {
+				t.Errorf("DiagnosticsRecorder.Record() returned %v warnings, want a warning %v", warnings, tt.wantWarning)
+			}
+
+			records := recorder.Records()
+			if len(records) != steps+1 {
+				t.Fatalf("DiagnosticsRecorder.Records() = %v records, want %v", len(records), steps+1)
+			}
+
+			// the center of mass moves uniformly and the momentum is conserved by both integrators
+			last := records[len(records)-1]
+			if last.Drift.CenterOfMass > 1e-9 || last.Drift.Momentum > 1e-9 {
+				t.Errorf("DiagnosticsRecorder.Records() drift = %+v", last.Drift)
+			}
+		})
+	}
+}
+
+func TestDiagnosticsRecorder_Record(t *testing.T) {
+	initial := []Star2D{
+		NewStar2D(Vec2{-1, 0}, Vec2{0, -1}, 1),
+		NewStar2D(Vec2{1, 0}, Vec2{0, 1}, 1),
+	}
+	changed := []Star2D{
+		NewStar2D(Vec2{-1, 0}, Vec2{0, -2}, 1),
+		NewStar2D(Vec2{1, 0}, Vec2{0, 2}, 1),
+	}
+
+	// the stars are moved away from the resting center of mass, all the other quantities stay the same
+	shifted := []Star2D{
+		NewStar2D(Vec2{0, 0}, Vec2{0, -1}, 1),
+		NewStar2D(Vec2{2, 0}, Vec2{0, 1}, 1),
+	}
+
+	// the warning is given when the tolerance is crossed, not as long as it is exceeded
+	recorder := NewDiagnosticsRecorder(0.1)
+	for i, tt := range []struct {
+		stars        []Star2D
+		wantWarnings []string
+	}{
+		{initial, nil},
+		{changed, []string{"energy", "angular momentum"}},
+		{changed, nil},
+		{initial, nil},
+		{changed, []string{"energy", "angular momentum"}},
+		{shifted, []string{"center of mass"}},
+		{shifted, nil},
+	} {
+		record := recorder.Record(float64(i), tt.stars, nil, 0, WithUnits(NBodyUnits))
+		if reflect.DeepEqual(record.Warnings, tt.wantWarnings) == false {
+			t.Errorf("DiagnosticsRecorder.Record() warnings = %v in record %v, want %v", record.Warnings, i, tt.wantWarnings)
+		}
+	}
+
+	// the records are stored along with their warnings
+	if records := recorder.Records(); len(records) != 7 || len(records[1].Warnings) != 2 {
+		t.Errorf("DiagnosticsRecorder.Records() = %+v", records)
+	}
+}
@@ -0,0 +1,152 @@
+// directSum.go calculates the accelerations and potentials of stars by summing up all pairs of stars
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+import (
+	"runtime"
+	"sync"
+)
+
+// directSumBlockSize is the amount of stars in a block of the pair matrix
+const directSumBlockSize = 64
+
+// DirectSum calculates the acceleration and the potential of every given star by summing up the contributions of
+// all other stars directly. The potential is the potential energy of the star divided by its mass.
+// Every pair of stars is only evaluated once: the force acting on both stars of a pair is the same apart from its
+// direction (Newton's third law). The stars are split into blocks and the pairs of blocks are handled in rounds by
+// the given amount of workers, if workers is smaller than 1, GOMAXPROCS workers are used. The pairs of blocks of a
+// round don't share any stars, so the contributions acting on a star are always summed up in the same order and
+// the results are the same bit for bit no matter how many workers are used or how they are scheduled. The options
+// define the softening and the unit system, the opening criterion and the quadrupole correction are not used.
+// The results are exact apart from rounding errors, which makes DirectSum the reference for the tree forces. The
+// amount of work grows with the square of the amount of stars, so it should only be used for small systems.
+func DirectSum(stars []Star2D, workers int, opts ...ForceOption) ([]Vec2, []float64) {
+	options := newForceOptions(opts)
+
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	// the stars of a pair of blocks are only written by the worker handling the pair, so that no locking is
+	// required
+	acceleration := make([]Vec2, len(stars))
+	potential := make([]float64, len(stars))
+
+	blocks := (len(stars) + directSumBlockSize - 1) / directSumBlockSize
+	for _, round := range directSumRounds(blocks) {
+		pairs := make(chan [2]int)
+		go func() {
+			for _, pair := range round {
+				pairs <- pair
+			}
+			close(pairs)
+		}()
+
+		var wg sync.WaitGroup
+		for w := 0; w < workers && w < len(round); w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for pair := range pairs {
+					directSumBlocks(stars, pair[0], pair[1], &options, acceleration, potential)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+
+	return acceleration, potential
+}
+
+// directSumRounds returns the pairs of blocks (i, j) with i <= j grouped into rounds, so that every block is part
+// of at most one pair of a round. The first round pairs every block with itself, the remaining rounds pair the
+// blocks with each other using the circle method of round-robin tournaments.
+func directSumRounds(blocks int) [][][2]int {
+	if blocks == 0 {
+		return nil
+	}
+
+	diagonal := make([][2]int, blocks)
+	for i := range diagonal {
+		diagonal[i] = [2]int{i, i}
+	}
+	rounds := [][][2]int{diagonal}
+
+	// an odd amount of blocks is padded by a block without any stars, the block paired with it pauses in that round
+	n := blocks + blocks%2
+	for r := 0; r < n-1; r++ {
+		var round [][2]int
+		for k := 0; k < n/2; k++ {
+			i, j := (r+k)%(n-1), (r+n-1-k)%(n-1)
+			if k == 0 {
+				j = n - 1
+			}
+			if j >= blocks {
+				continue
+			}
+			if i > j {
+				i, j = j, i
+			}
+			round = append(round, [2]int{i, j})
+		}
+		rounds = append(rounds, round)
+	}
+	return rounds
+}
+
+// directSumBlocks adds the contributions of all the pairs of stars in between the blocks i and j to the
+// accelerations and the potentials of the stars. A block paired with itself only handles each pair once.
+func directSumBlocks(stars []Star2D, i, j int, options *forceOptions, acceleration []Vec2, potential []float64) {
+	G := options.units.G()
+
+	start1, end1 := i*directSumBlockSize, (i+1)*directSumBlockSize
+	start2, end2 := j*directSumBlockSize, (j+1)*directSumBlockSize
+	if end1 > len(stars) {
+		end1 = len(stars)
+	}
+	if end2 > len(stars) {
+		end2 = len(stars)
+	}
+
+	for a := start1; a < end1; a++ {
+		first := start2
+		if i == j {
+			first = a + 1
+		}
+
+		for b := first; b < end2; b++ {
+			s1, s2 := stars[a], stars[b]
+
+			var vector Vec2 = s2.C.Sub(s1.C)
+			var distanceSquared float64 = vector.Len2()
+
+			length := options.softening(s1, s2)
+			kernel := options.kernel
+			if length <= 0 {
+				kernel = NoSoftening
+			}
+
+			force := vector.Multiply(G * softenedForceFactor(kernel, length, distanceSquared))
+			pairPotential := -G * softenedPotentialFactor(kernel, length, distanceSquared)
+
+			acceleration[a] = acceleration[a].Add(force.Multiply(s2.M))
+			acceleration[b] = acceleration[b].Add(force.Multiply(-s1.M))
+			potential[a] += pairPotential * s2.M
+			potential[b] += pairPotential * s1.M
+		}
+	}
+}
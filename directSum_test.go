@@ -0,0 +1,221 @@
+// directSum_test.go provides tests for directSum.go
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// DirectSum calculates the accelerations and potentials of all the stars at once
+func ExampleDirectSum() {
+	stars := []Star2D{
+		NewStar2D(Vec2{0, 0}, Vec2{0, 0}, 1),
+		NewStar2D(Vec2{3, 4}, Vec2{0, 0}, 2),
+	}
+
+	accelerations, potentials := DirectSum(stars, 1, WithUnits(NBodyUnits))
+	fmt.Printf("%.2f %.2f\n", accelerations, potentials)
+	// Output:
+	// [{0.05 0.06} {-0.02 -0.03}] [-0.40 -0.20]
+}
+
+func TestDirectSum(t *testing.T) {
+	tests := []struct {
+		name    string
+		stars   []Star2D
+		workers int
+		opts    []ForceOption
+	}{
+		{
+			name:    "no stars",
+			stars:   []Star2D{},
+			workers: 4,
+		},
+		{
+			name:    "single star",
+			stars:   []Star2D{NewStar2D(Vec2{1, 2}, Vec2{0, 0}, 3)},
+			workers: 4,
+		},
+		{
+			name:    "single worker",
+			stars:   randomStars(300, 100, 14),
+			workers: 1,
+		},
+		{
+			name:    "many workers",
+			stars:   randomStars(1000, 100, 15),
+			workers: 8,
+		},
+		{
+			name:    "default amount of workers",
+			stars:   randomStars(200, 100, 16),
+			workers: 0,
+		},
+		{
+			name:    "plummer softening in N-body units",
+			stars:   randomStars(200, 100, 17),
+			workers: 3,
+			opts:    []ForceOption{WithSoftening(PlummerSoftening, 1), WithUnits(NBodyUnits)},
+		},
+		{
+			name:    "spline softening",
+			stars:   randomStars(200, 100, 18),
+			workers: 3,
+			opts:    []ForceOption{WithSoftening(SplineSoftening, 3)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := newForceOptions(tt.opts)
+			accelerations, potentials := DirectSum(tt.stars, tt.workers, tt.opts...)
+			if len(accelerations) != len(tt.stars) || len(potentials) != len(tt.stars) {
+				t.Fatalf("DirectSum() returned %v accelerations and %v potentials, want %v",
+					len(accelerations), len(potentials), len(tt.stars))
+			}
+
+			momentum := Vec2{}
+			for i, star := range tt.stars {
+				wantAcceleration := Vec2{}
+				wantPotential := 0.0
+				for j, other := range tt.stars {
+					if i == j {
+						continue
+					}
+					force := CalcForce(star, other, tt.opts...)
+					wantAcceleration = wantAcceleration.Add(force.Multiply(1 / star.M))
+
+					length := options.softening(star, other)
+					distanceSquared := math.Pow(star.C.X-other.C.X, 2) + math.Pow(star.C.Y-other.C.Y, 2)
+					wantPotential -= options.units.G() * other.M *
+						softenedPotentialFactor(options.kernel, length, distanceSquared)
+				}
+
+				got := accelerations[i]
+				if math.Hypot(got.X-wantAcceleration.X, got.Y-wantAcceleration.Y) >
+					1e-9*math.Hypot(wantAcceleration.X, wantAcceleration.Y) {
+					t.Errorf("DirectSum() acceleration = %v, want %v", got, wantAcceleration)
+				}
+				if math.Abs(potentials[i]-wantPotential) > 1e-9*math.Abs(wantPotential) {
+					t.Errorf("DirectSum() potential = %v, want %v", potentials[i], wantPotential)
+				}
+
+				momentum = momentum.Add(got.Multiply(star.M))
+			}
+
+			// the results don't depend on the amount of workers, not even in the last bit
+			for _, workers := range []int{1, 2, 7} {
+				otherAccelerations, otherPotentials := DirectSum(tt.stars, workers, tt.opts...)
+				if reflect.DeepEqual(otherAccelerations, accelerations) == false || reflect.DeepEqual(otherPotentials, potentials) == false {
+					t.Errorf("DirectSum() using %v workers differs from the results using %v workers", workers, tt.workers)
+				}
+			}
+
+			// the forces acting on the stars cancel out
+			scale := 0.0
+			for i, acceleration := range accelerations {
+				scale += tt.stars[i].M * math.Hypot(acceleration.X, acceleration.Y)
+			}
+			if math.Hypot(momentum.X, momentum.Y) > 1e-12*scale {
+				t.Errorf("DirectSum() total force = %v, want {0 0}", momentum)
+			}
+		})
+	}
+}
+
+func TestDirectSumRounds(t *testing.T) {
+	for _, blocks := range []int{0, 1, 2, 5, 8} {
+		pairs := map[[2]int]int{}
+		for r, round := range directSumRounds(blocks) {
+
+			// the pairs of a round must not share a block, as they are handled concurrently
+			used := map[int]bool{}
+			for _, pair := range round {
+				if used[pair[0]] || (pair[1] != pair[0] && used[pair[1]]) {
+					t.Errorf("directSumRounds(%v) uses a block twice in round %v: %v", blocks, r, round)
+				}
+				used[pair[0]], used[pair[1]] = true, true
+				pairs[pair]++
+			}
+		}
+
+		// every pair of blocks is handled exactly once
+		if len(pairs) != blocks*(blocks+1)/2 {
+			t.Errorf("directSumRounds(%v) = %v pairs, want %v", blocks, len(pairs), blocks*(blocks+1)/2)
+		}
+		for pair, count := range pairs {
+			if pair[0] > pair[1] || pair[1] >= blocks || count != 1 {
+				t.Errorf("directSumRounds(%v) handles the pair %v %v times", blocks, pair, count)
+			}
+		}
+	}
+}
+
+func TestSoftenedPotentialFactor(t *testing.T) {
+
+	// the force is the negative derivative of the potential
+	for _, kernel := range []SofteningKernel{NoSoftening, PlummerSoftening, SplineSoftening} {
+		for _, distance := range []float64{0.3, 1, 1.5, 2, 2.7, 3, 10} {
+			const step = 1e-6
+			derivative := (softenedPotentialFactor(kernel, 1, math.Pow(distance+step, 2)) -
+				softenedPotentialFactor(kernel, 1, math.Pow(distance-step, 2))) / (2 * step)
+			want := -distance * softenedForceFactor(kernel, 1, distance*distance)
+			if math.Abs(derivative-want) > 1e-6*math.Abs(want) {
+				t.Errorf("softenedPotentialFactor() derivative = %v at %v using kernel %v, want %v",
+					derivative, distance, kernel, want)
+			}
+		}
+	}
+
+	// the spline potential equals the plummer potential at the center
+	if got, want := softenedPotentialFactor(SplineSoftening, 2, 0), softenedPotentialFactor(PlummerSoftening, 2, 0); math.Abs(got-want) > 1e-9 {
+		t.Errorf("softenedPotentialFactor() = %v at the center, want %v", got, want)
+	}
+}
+
+func TestDirectSum_barnesHut(t *testing.T) {
+	stars := randomStars(3000, 100, 19)
+	root, err := BuildTree(stars)
+	if err != nil {
+		t.Fatalf("BuildTree() error = %v", err)
+	}
+
+	accelerations, _ := DirectSum(stars, 0)
+
+	// the error of the tree forces shrinks with theta
+	previousError := math.Inf(1)
+	for _, theta := range []float64{1, 0.7, 0.5, 0.3, 0.1} {
+		meanError := 0.0
+		for i, star := range stars[:100] {
+			want := accelerations[i]
+			got := root.CalcAllForces(star, theta)
+			got = got.Multiply(1 / star.M)
+			meanError += math.Hypot(got.X-want.X, got.Y-want.Y) / math.Hypot(want.X, want.Y) / 100
+		}
+
+		if meanError >= previousError {
+			t.Errorf("Node.CalcAllForces() mean error = %v using theta %v, want less than %v",
+				meanError, theta, previousError)
+		}
+		previousError = meanError
+	}
+	if previousError > 1e-3 {
+		t.Errorf("Node.CalcAllForces() mean error = %v using theta 0.1, want at most 1e-3", previousError)
+	}
+}
@@ -0,0 +1,263 @@
+// externalPotential.go defines analytic potentials acting on the stars in addition to their own gravity
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+import "math"
+
+// ExternalPotential is an analytic potential, for example of a dark matter halo, a bulge or a disk, acting on the
+// stars in addition to the gravity of the stars themselves. Positions, times, accelerations and potentials (energy
+// per mass) are given in the unit system of the stars.
+type ExternalPotential interface {
+
+	// Acceleration returns the acceleration caused by the potential at the given position at the time t
+	Acceleration(position Vec2, t float64) Vec2
+
+	// Potential returns the potential at the given position at the time t
+	Potential(position Vec2, t float64) float64
+}
+
+// MovingCenter defines the center of an external potential. The center moves from Center with the constant
+// Velocity, if Path is set, it defines the position of the center at the time t instead.
+type MovingCenter struct {
+	Center   Vec2                 // Position of the center at the time 0
+	Velocity Vec2                 // Velocity of the center
+	Path     func(t float64) Vec2 // Position of the center at the time t, overrides Center and Velocity
+}
+
+// at returns the position of the center at the time t
+func (c MovingCenter) at(t float64) Vec2 {
+	if c.Path != nil {
+		return c.Path(t)
+	}
+	return c.Center.Add(c.Velocity.Multiply(t))
+}
+
+// offset returns the position relative to the center at the time t and its length
+func (c MovingCenter) offset(position Vec2, t float64) (Vec2, float64) {
+	offset := position.Sub(c.at(t))
+	return offset, offset.Len()
+}
+
+// radialAcceleration returns the acceleration of the given magnitude pointing from the offset towards the center
+func radialAcceleration(offset Vec2, radius float64, magnitude float64) Vec2 {
+	if radius == 0 {
+		return Vec2{}
+	}
+	return offset.Multiply(-magnitude / radius)
+}
+
+// NFW is the potential of the Navarro-Frenk-White dark matter halo with the density
+// rho(r) = rho0 / ((r/rs) * (1 + r/rs)^2). The potential is -G*M0*ln(1 + r/rs)/r using the scale mass
+// M0 = 4*pi*rho0*rs^3. The enclosed mass grows logarithmically, so the rotation curve stays nearly flat far out.
+type NFW struct {
+	MovingCenter
+	ScaleMass   float64 // M0 = 4*pi*rho0*rs^3
+	ScaleRadius float64 // rs
+	Units       Units   // Unit system defining the gravitational constant
+}
+
+// Acceleration returns -G*M(r)/r^2 using the enclosed mass M(r) = M0 * (ln(1+x) - x/(1+x)), x = r/rs
+func (p NFW) Acceleration(position Vec2, t float64) Vec2 {
+	offset, r := p.offset(position, t)
+	if r == 0 {
+		return Vec2{}
+	}
+
+	x := r / p.ScaleRadius
+	enclosedMass := p.ScaleMass * (math.Log1p(x) - x/(1+x))
+	return radialAcceleration(offset, r, p.Units.G()*enclosedMass/(r*r))
+}
+
+// Potential returns -G*M0*ln(1 + r/rs)/r, which is -G*M0/rs at the center
+func (p NFW) Potential(position Vec2, t float64) float64 {
+	_, r := p.offset(position, t)
+	if r == 0 {
+		return -p.Units.G() * p.ScaleMass / p.ScaleRadius
+	}
+	return -p.Units.G() * p.ScaleMass * math.Log1p(r/p.ScaleRadius) / r
+}
+
+// Hernquist is the potential -G*M/(r + a) of a Hernquist bulge of the total mass M and the scale radius a
+type Hernquist struct {
+	MovingCenter
+	Mass        float64 // M
+	ScaleRadius float64 // a
+	Units       Units   // Unit system defining the gravitational constant
+}
+
+// Acceleration returns -G*M/(r + a)^2
+func (p Hernquist) Acceleration(position Vec2, t float64) Vec2 {
+	offset, r := p.offset(position, t)
+	return radialAcceleration(offset, r, p.Units.G()*p.Mass/((r+p.ScaleRadius)*(r+p.ScaleRadius)))
+}
+
+// Potential returns -G*M/(r + a)
+func (p Hernquist) Potential(position Vec2, t float64) float64 {
+	_, r := p.offset(position, t)
+	return -p.Units.G() * p.Mass / (r + p.ScaleRadius)
+}
+
+// Plummer is the potential -G*M/sqrt(r^2 + b^2) of a Plummer sphere of the total mass M and the scale length b
+type Plummer struct {
+	MovingCenter
+	Mass        float64 // M
+	ScaleLength float64 // b
+	Units       Units   // Unit system defining the gravitational constant
+}
+
+// Acceleration returns -G*M*r/(r^2 + b^2)^(3/2)
+func (p Plummer) Acceleration(position Vec2, t float64) Vec2 {
+	offset, r := p.offset(position, t)
+	softenedSquared := r*r + p.ScaleLength*p.ScaleLength
+	return offset.Multiply(-p.Units.G() * p.Mass / (softenedSquared * math.Sqrt(softenedSquared)))
+}
+
+// Potential returns -G*M/sqrt(r^2 + b^2)
+func (p Plummer) Potential(position Vec2, t float64) float64 {
+	_, r := p.offset(position, t)
+	return -p.Units.G() * p.Mass / math.Sqrt(r*r+p.ScaleLength*p.ScaleLength)
+}
+
+// Isothermal is the potential of a (pseudo-)isothermal sphere with the density rho0 / (1 + (r/rc)^2). Far away
+// from the core, the circular velocity approaches V. Without a core radius, it is the singular isothermal sphere
+// V^2 * ln(r) with the constant circular velocity V.
+type Isothermal struct {
+	MovingCenter
+	CircularVelocity float64 // V = sqrt(4*pi*G*rho0*rc^2), the circular velocity far away from the core
+	CoreRadius       float64 // rc
+}
+
+// Acceleration returns -V^2/r * (1 - arctan(r/rc)/(r/rc))
+func (p Isothermal) Acceleration(position Vec2, t float64) Vec2 {
+	offset, r := p.offset(position, t)
+	if r == 0 {
+		return Vec2{}
+	}
+
+	factor := 1.0
+	if p.CoreRadius > 0 {
+		x := r / p.CoreRadius
+		factor = 1 - math.Atan(x)/x
+	}
+	return radialAcceleration(offset, r, p.CircularVelocity*p.CircularVelocity/r*factor)
+}
+
+// Potential returns V^2 * (ln(1 + x^2)/2 + arctan(x)/x - 1) using x = r/rc, which is 0 at the center. Without a core
+// radius, V^2 * ln(r) is returned.
+func (p Isothermal) Potential(position Vec2, t float64) float64 {
+	_, r := p.offset(position, t)
+	v2 := p.CircularVelocity * p.CircularVelocity
+
+	if p.CoreRadius <= 0 {
+		return v2 * math.Log(r)
+	}
+	if r == 0 {
+		return 0
+	}
+
+	x := r / p.CoreRadius
+	return v2 * (math.Log1p(x*x)/2 + math.Atan(x)/x - 1)
+}
+
+// Logarithmic is the potential V0^2/2 * ln(Rc^2 + x^2 + y^2/q^2) of a halo with the flattening q. Far away from the
+// core, the circular velocity approaches V0 (Binney & Tremaine 2.71).
+type Logarithmic struct {
+	MovingCenter
+	Velocity0  float64 // V0, the circular velocity far away from the core
+	CoreRadius float64 // Rc
+	Flattening float64 // q, 1 for a round halo. A flattening of zero is treated as 1.
+}
+
+// flattening returns the configured flattening or 1 if it isn't configured
+func (p Logarithmic) flattening() float64 {
+	if p.Flattening == 0 {
+		return 1
+	}
+	return p.Flattening
+}
+
+// Acceleration returns the negative gradient of the potential
+func (p Logarithmic) Acceleration(position Vec2, t float64) Vec2 {
+	offset, _ := p.offset(position, t)
+	q2 := p.flattening() * p.flattening()
+
+	denominator := p.CoreRadius*p.CoreRadius + offset.X*offset.X + offset.Y*offset.Y/q2
+	if denominator == 0 {
+		return Vec2{}
+	}
+
+	v2 := p.Velocity0 * p.Velocity0
+	return Vec2{-v2 * offset.X / denominator, -v2 * offset.Y / q2 / denominator}
+}
+
+// Potential returns V0^2/2 * ln(Rc^2 + x^2 + y^2/q^2)
+func (p Logarithmic) Potential(position Vec2, t float64) float64 {
+	offset, _ := p.offset(position, t)
+	q2 := p.flattening() * p.flattening()
+	return p.Velocity0 * p.Velocity0 / 2 * math.Log(p.CoreRadius*p.CoreRadius+offset.X*offset.X+offset.Y*offset.Y/q2)
+}
+
+// Kuzmin is the potential -G*M/sqrt(R^2 + (a + |z|)^2) of an infinitely thin Kuzmin disk of the total mass M and the
+// scale length a, evaluated in the plane of the disk (z = 0). In the plane, it equals the potential of a Plummer
+// sphere, but the mass is distributed with the surface density M*a / (2*pi*(R^2 + a^2)^(3/2)).
+type Kuzmin struct {
+	MovingCenter
+	Mass        float64 // M
+	ScaleLength float64 // a
+	Units       Units   // Unit system defining the gravitational constant
+}
+
+// plummer returns the Plummer sphere having the same potential in the plane of the disk
+func (p Kuzmin) plummer() Plummer {
+	return Plummer{MovingCenter: p.MovingCenter, Mass: p.Mass, ScaleLength: p.ScaleLength, Units: p.Units}
+}
+
+// Acceleration returns -G*M*R/(R^2 + a^2)^(3/2)
+func (p Kuzmin) Acceleration(position Vec2, t float64) Vec2 {
+	return p.plummer().Acceleration(position, t)
+}
+
+// Potential returns -G*M/sqrt(R^2 + a^2)
+func (p Kuzmin) Potential(position Vec2, t float64) float64 {
+	return p.plummer().Potential(position, t)
+}
+
+// ExternalAccelerations is an AccelerationProvider adding the accelerations caused by external potentials to the
+// accelerations calculated by another provider, for example TreeAccelerations
+type ExternalAccelerations struct {
+	Provider   AccelerationProvider // Provider calculating the accelerations of the stars, may be nil
+	Potentials []ExternalPotential  // External potentials acting on the stars
+}
+
+// Accelerations returns the accelerations of the provider plus the accelerations caused by the potentials
+func (p ExternalAccelerations) Accelerations(stars []Star2D, t float64) ([]Vec2, error) {
+	accelerations := make([]Vec2, len(stars))
+	if p.Provider != nil {
+		var err error
+		accelerations, err = p.Provider.Accelerations(stars, t)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i, star := range stars {
+		for _, potential := range p.Potentials {
+			accelerations[i] = accelerations[i].Add(potential.Acceleration(star.C, t))
+		}
+	}
+	return accelerations, nil
+}
@@ -0,0 +1,188 @@
+// externalPotential_test.go provides tests for externalPotential.go
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// External potentials are added to the accelerations of the stars using ExternalAccelerations
+func ExampleExternalAccelerations() {
+	stars := []Star2D{
+		NewStar2D(Vec2{8, 0}, Vec2{0, 0}, 1),
+	}
+
+	halo := Isothermal{CircularVelocity: 200, CoreRadius: 0}
+	bulge := Hernquist{Mass: 1e10, ScaleRadius: 1, Units: GalacticUnits}
+	provider := ExternalAccelerations{Potentials: []ExternalPotential{halo, bulge}}
+
+	accelerations, err := provider.Accelerations(stars, 0)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("%.1f\n", accelerations[0])
+	// Output:
+	// {-5555.2 0.0}
+}
+
+func TestExternalPotential(t *testing.T) {
+	moving := MovingCenter{Center: Vec2{1, -2}, Velocity: Vec2{0.5, 0.25}}
+	circling := MovingCenter{Path: func(t float64) Vec2 {
+		return Vec2{3 * math.Cos(t), 3 * math.Sin(t)}
+	}}
+
+	tests := []struct {
+		name      string
+		potential ExternalPotential
+	}{
+		{
+			name:      "NFW",
+			potential: NFW{MovingCenter: moving, ScaleMass: 1e11, ScaleRadius: 20, Units: GalacticUnits},
+		},
+		{
+			name:      "Hernquist",
+			potential: Hernquist{MovingCenter: circling, Mass: 1e10, ScaleRadius: 1, Units: GalacticUnits},
+		},
+		{
+			name:      "Plummer",
+			potential: Plummer{MovingCenter: moving, Mass: 5, ScaleLength: 2, Units: NBodyUnits},
+		},
+		{
+			name:      "Isothermal",
+			potential: Isothermal{MovingCenter: moving, CircularVelocity: 200, CoreRadius: 3},
+		},
+		{
+			name:      "singular Isothermal",
+			potential: Isothermal{MovingCenter: circling, CircularVelocity: 200},
+		},
+		{
+			name:      "Logarithmic",
+			potential: Logarithmic{MovingCenter: moving, Velocity0: 200, CoreRadius: 1, Flattening: 0.8},
+		},
+		{
+			name:      "round Logarithmic",
+			potential: Logarithmic{Velocity0: 200, CoreRadius: 1},
+		},
+		{
+			name:      "Kuzmin",
+			potential: Kuzmin{MovingCenter: circling, Mass: 1e10, ScaleLength: 3, Units: GalacticUnits},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			// the acceleration is the negative gradient of the potential
+			const step = 1e-5
+			for _, position := range []Vec2{{0.3, 0.1}, {5, -4}, {-12, 30}, {100, 80}} {
+				for _, time := range []float64{0, 2} {
+					want := tt.potential.Acceleration(position, time)
+					got := Vec2{
+						X: -(tt.potential.Potential(Vec2{position.X + step, position.Y}, time) -
+							tt.potential.Potential(Vec2{position.X - step, position.Y}, time)) / (2 * step),
+						Y: -(tt.potential.Potential(Vec2{position.X, position.Y + step}, time) -
+							tt.potential.Potential(Vec2{position.X, position.Y - step}, time)) / (2 * step),
+					}
+					if math.Hypot(got.X-want.X, got.Y-want.Y) > 1e-5*math.Hypot(want.X, want.Y) {
+						t.Errorf("%v.Acceleration() = %v at %v, want the negative gradient %v",
+							tt.name, want, position, got)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestExternalPotential_circularVelocity(t *testing.T) {
+	circularVelocity := func(potential ExternalPotential, radius float64) float64 {
+		acceleration := potential.Acceleration(Vec2{radius, 0}, 0)
+		return math.Sqrt(-acceleration.X * radius)
+	}
+
+	tests := []struct {
+		name      string
+		potential ExternalPotential
+		radius    float64
+		want      float64
+	}{
+		{
+			name:      "singular isothermal sphere",
+			potential: Isothermal{CircularVelocity: 200},
+			radius:    3,
+			want:      200,
+		},
+		{
+			name:      "isothermal sphere far away from the core",
+			potential: Isothermal{CircularVelocity: 200, CoreRadius: 0.01},
+			radius:    1000,
+			want:      200,
+		},
+		{
+			name:      "logarithmic halo far away from the core",
+			potential: Logarithmic{Velocity0: 200, CoreRadius: 0.01},
+			radius:    1000,
+			want:      200,
+		},
+		{
+			name:      "hernquist bulge far away",
+			potential: Hernquist{Mass: 1, ScaleRadius: 1e-6, Units: NBodyUnits},
+			radius:    1,
+			want:      1,
+		},
+		{
+			name:      "NFW halo at 2.163 scale radii, where the circular velocity peaks",
+			potential: NFW{ScaleMass: 1, ScaleRadius: 1, Units: NBodyUnits},
+			radius:    2.16258,
+			want:      math.Sqrt((math.Log1p(2.16258) - 2.16258/3.16258) / 2.16258),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := circularVelocity(tt.potential, tt.radius); math.Abs(got-tt.want) > 1e-4*tt.want {
+				t.Errorf("circular velocity = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExternalAccelerations(t *testing.T) {
+	stars := randomStars(50, 10, 26)
+	opts := []ForceOption{WithUnits(NBodyUnits)}
+
+	halo := NFW{ScaleMass: 100, ScaleRadius: 5, Units: NBodyUnits}
+	disk := Kuzmin{MovingCenter: MovingCenter{Velocity: Vec2{1, 0}}, Mass: 10, ScaleLength: 2, Units: NBodyUnits}
+
+	provider := ExternalAccelerations{
+		Provider:   DirectAccelerations{Options: opts},
+		Potentials: []ExternalPotential{halo, disk},
+	}
+	got, err := provider.Accelerations(stars, 3)
+	if err != nil {
+		t.Fatalf("ExternalAccelerations.Accelerations() error = %v", err)
+	}
+
+	want, _ := DirectSum(stars, 0, opts...)
+	for i, star := range stars {
+		want[i] = want[i].Add(halo.Acceleration(star.C, 3))
+		want[i] = want[i].Add(disk.Acceleration(star.C, 3))
+		if math.Hypot(got[i].X-want[i].X, got[i].Y-want[i].Y) > 1e-12*math.Hypot(want[i].X, want[i].Y) {
+			t.Errorf("ExternalAccelerations.Accelerations() = %v, want %v", got[i], want[i])
+		}
+	}
+}
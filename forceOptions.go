@@ -0,0 +1,60 @@
+// forceOptions.go defines the options used while calculating the forces acting on stars
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+// forceOptions defines how the forces acting on a star are calculated. The zero value opens cells using the
+// ClassicCriterion, approximates every accepted cell as a single point mass at its center of mass, does not
+// soften the forces and uses SI units.
+type forceOptions struct {
+	quadrupole           bool             // add the quadrupole correction of accepted cells
+	criterion            OpeningCriterion // criterion deciding whether a cell is opened
+	previousAcceleration Vec2             // acceleration of the star in the previous step
+
+	previousAcceleration3D Vec3 // acceleration of the star in the previous step, used by Node3D
+
+	previousAccelerations []Vec2 // acceleration of every star in the previous step, used by ComputeAccelerations
+
+	units     Units // unit system the stars are given in
+	treeOrder bool  // handle the stars in the order of their morton keys
+
+	kernel            SofteningKernel           // kernel used to soften the forces
+	softeningLength   float64                   // softening length used for all stars
+	adaptiveSoftening func(star Star2D) float64 // softening length of every single star, overrides softeningLength
+
+	adaptiveSoftening3D func(star Star3D) float64 // softening length of every single star, used by Node3D
+}
+
+// ForceOption configures the calculation of the forces acting on a star
+type ForceOption func(options *forceOptions)
+
+// WithQuadrupole adds the force exerted by the quadrupole moment of a cell to the force of its monopole whenever a
+// cell is accepted by the opening criterion. This reduces the error of the approximation, so a larger theta can be
+// used for the same accuracy.
+func WithQuadrupole() ForceOption {
+	return func(options *forceOptions) {
+		options.quadrupole = true
+	}
+}
+
+// newForceOptions applies the given options to the default options
+func newForceOptions(opts []ForceOption) forceOptions {
+	options := forceOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
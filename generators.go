@@ -0,0 +1,361 @@
+// generators.go generates the stars of galaxies in equilibrium using standard galaxy models
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+)
+
+// ErrInvalidModel is returned when a galaxy can't be generated using the given model or configuration
+var ErrInvalidModel = errors.New("invalid galaxy model")
+
+// toomreStellarFactor is the factor 3.36 in the Toomre stability criterion Q = sigma*kappa/(3.36*G*Sigma) of a
+// stellar disk
+const toomreStellarFactor = 3.36
+
+// GalaxyModel defines the radial mass profile of a galaxy. The spherical models are flattened into the plane of the
+// simulation keeping their radial mass profile, so the stars are placed at the radii they would have in three
+// dimensions.
+type GalaxyModel interface {
+
+	// EnclosedMass returns the mass inside of the radius r
+	EnclosedMass(r float64) float64
+
+	// ScaleRadius returns the characteristic radius of the model
+	ScaleRadius() float64
+}
+
+// DiskModel is implemented by the models of infinitely thin disks. A thin disk doesn't attract its stars like a
+// sphere of the same enclosed mass would, so GenerateGalaxy calculates their circular velocity using the radial
+// acceleration of the disk itself instead of G*M(<r)/r.
+type DiskModel interface {
+	GalaxyModel
+
+	// RadialAcceleration returns the magnitude of the acceleration towards the center at the radius r in the plane
+	// of the disk using the gravitational constant G
+	RadialAcceleration(G float64, r float64) float64
+}
+
+// PlummerModel is a Plummer sphere of the total mass M and the scale length a with M(r) = M*r^3/(r^2+a^2)^(3/2)
+type PlummerModel struct {
+	Mass        float64
+	ScaleLength float64
+}
+
+// EnclosedMass returns M*r^3/(r^2+a^2)^(3/2)
+func (m PlummerModel) EnclosedMass(r float64) float64 {
+	return m.Mass * r * r * r / math.Pow(r*r+m.ScaleLength*m.ScaleLength, 1.5)
+}
+
+// ScaleRadius returns the scale length a
+func (m PlummerModel) ScaleRadius() float64 {
+	return m.ScaleLength
+}
+
+// HernquistModel is a Hernquist bulge of the total mass M and the scale radius a with M(r) = M*r^2/(r+a)^2
+type HernquistModel struct {
+	Mass        float64
+	ScaleLength float64
+}
+
+// EnclosedMass returns M*r^2/(r+a)^2
+func (m HernquistModel) EnclosedMass(r float64) float64 {
+	return m.Mass * r * r / ((r + m.ScaleLength) * (r + m.ScaleLength))
+}
+
+// ScaleRadius returns the scale radius a
+func (m HernquistModel) ScaleRadius() float64 {
+	return m.ScaleLength
+}
+
+// ExponentialDiskModel is an exponential disk of the total mass M and the scale length Rd with the surface density
+// M/(2*pi*Rd^2) * exp(-R/Rd)
+type ExponentialDiskModel struct {
+	Mass        float64
+	ScaleLength float64
+}
+
+// EnclosedMass returns M * (1 - (1 + R/Rd) * exp(-R/Rd))
+func (m ExponentialDiskModel) EnclosedMass(r float64) float64 {
+	x := r / m.ScaleLength
+	return m.Mass * (1 - (1+x)*math.Exp(-x))
+}
+
+// ScaleRadius returns the scale length Rd
+func (m ExponentialDiskModel) ScaleRadius() float64 {
+	return m.ScaleLength
+}
+
+// RadialAcceleration returns 2*G*M/Rd * y^2 * (I0(y)*K0(y) - I1(y)*K1(y)) / r using y = r/(2*Rd) (Freeman 1970)
+func (m ExponentialDiskModel) RadialAcceleration(G float64, r float64) float64 {
+	if r <= 0 {
+		return 0
+	}
+
+	// the exponential factors of the scaled bessel functions cancel out
+	y := r / (2 * m.ScaleLength)
+	bessel := scaledBesselI(0, y)*scaledBesselK(0, y) - scaledBesselI(1, y)*scaledBesselK(1, y)
+	return 2 * G * m.Mass / m.ScaleLength * y * y * bessel / r
+}
+
+// KuzminDiskModel is a Kuzmin disk of the total mass M and the scale length a with the surface density
+// M*a/(2*pi*(R^2+a^2)^(3/2))
+type KuzminDiskModel struct {
+	Mass        float64
+	ScaleLength float64
+}
+
+// EnclosedMass returns M * (1 - a/sqrt(R^2+a^2))
+func (m KuzminDiskModel) EnclosedMass(r float64) float64 {
+	return m.Mass * (1 - m.ScaleLength/math.Sqrt(r*r+m.ScaleLength*m.ScaleLength))
+}
+
+// ScaleRadius returns the scale length a
+func (m KuzminDiskModel) ScaleRadius() float64 {
+	return m.ScaleLength
+}
+
+// RadialAcceleration returns G*M*r/(r^2+a^2)^(3/2)
+func (m KuzminDiskModel) RadialAcceleration(G float64, r float64) float64 {
+	return G * m.Mass * r / math.Pow(r*r+m.ScaleLength*m.ScaleLength, 1.5)
+}
+
+// UniformDiskModel is a disk of the total mass M with a constant surface density up to the radius R
+type UniformDiskModel struct {
+	Mass   float64
+	Radius float64
+}
+
+// EnclosedMass returns M*r^2/R^2 inside of the disk and M outside of it
+func (m UniformDiskModel) EnclosedMass(r float64) float64 {
+	if r >= m.Radius {
+		return m.Mass
+	}
+	return m.Mass * r * r / (m.Radius * m.Radius)
+}
+
+// ScaleRadius returns the radius R of the disk
+func (m UniformDiskModel) ScaleRadius() float64 {
+	return m.Radius
+}
+
+// RadialAcceleration returns 4*G*Sigma * (K(k) - E(k)) / k inside of the disk using the complete elliptic integrals
+// of the modulus k = r/R and 4*G*Sigma * (K(k) - E(k)) outside of it using k = R/r. It diverges logarithmically at
+// the edge of the disk.
+func (m UniformDiskModel) RadialAcceleration(G float64, r float64) float64 {
+	if r <= 0 {
+		return 0
+	}
+
+	sigma := m.Mass / (math.Pi * m.Radius * m.Radius)
+	if r > m.Radius {
+		return 4 * G * sigma * ellipticKMinusE(m.Radius/r)
+	}
+
+	k := r / m.Radius
+	return 4 * G * sigma * ellipticKMinusE(k) / k
+}
+
+// GeneratorConfig defines how the stars of a galaxy are generated
+type GeneratorConfig struct {
+	Stars     int     // Amount of stars
+	Seed      int64   // Seed of the random numbers, the same seed results in the same stars
+	Units     Units   // Unit system the model is given in and the stars are generated in
+	ToomreQ   float64 // Toomre Q the velocity dispersion is tuned to, a cold galaxy is generated if it is zero
+	MaxRadius float64 // Radius the model is truncated at, 10 scale radii are used if it is zero
+
+	// Potentials are external potentials, such as a dark matter halo, contributing to the circular velocity
+	Potentials []ExternalPotential
+
+	// IMF is the initial mass function the masses of the stars are drawn from, they are scaled to the mass of the
+	// model. All stars have the same mass if it is nil.
+	IMF IMF
+}
+
+// GenerateGalaxy generates the stars of a galaxy using the given model. The stars are centered at the origin and
+// rotate counterclockwise. All the stars have the same mass or a mass drawn from the IMF, together they have the
+// mass of the model inside of the MaxRadius.
+// The velocity of a star is the circular velocity sqrt(G*M(<r)/r - r*a_ext) using the enclosed mass and the
+// radial acceleration of the external potentials, plus a random velocity. The enclosed mass term of a DiskModel is
+// replaced by r times the radial acceleration of the disk. The radial dispersion of the random
+// velocities is chosen so that the disk has the Toomre Q sigma*kappa/(3.36*G*Sigma) everywhere, the tangential
+// dispersion follows from the epicyclic approximation sigma_phi = sigma*kappa/(2*Omega).
+func GenerateGalaxy(model GalaxyModel, config GeneratorConfig) ([]Star2D, error) {
+	if config.Stars < 0 || model.ScaleRadius() <= 0 || config.ToomreQ < 0 {
+		return nil, ErrInvalidModel
+	}
+
+	maxRadius := config.MaxRadius
+	if maxRadius <= 0 {
+		maxRadius = 10 * model.ScaleRadius()
+	}
+	totalMass := model.EnclosedMass(maxRadius)
+	if totalMass <= 0 || math.IsInf(totalMass, 0) || math.IsNaN(totalMass) {
+		return nil, ErrInvalidModel
+	}
+
+	random := rand.New(rand.NewSource(config.Seed))
+	G := config.Units.G()
+
+	stars := make([]Star2D, config.Stars)
+	for i := range stars {
+
+		// draw the radius from the enclosed mass and the angle uniformly
+		r := enclosedMassRadius(model, random.Float64()*totalMass, maxRadius)
+		angle := random.Float64() * 2 * math.Pi
+		radial := NewVec2Polar(1, angle)
+		tangential := radial.Perp()
+
+		// the galaxy rotates using the circular velocity
+		circularVelocity := galaxyCircularVelocity(model, config.Potentials, G, r, angle)
+		velocity := tangential.Multiply(circularVelocity)
+
+		// the random velocities are tuned to the Toomre Q. The random numbers are always drawn, so the positions
+		// generated using a seed don't depend on the Toomre Q.
+		radialNoise, tangentialNoise := random.NormFloat64(), random.NormFloat64()
+		if config.ToomreQ > 0 && circularVelocity > 0 {
+			kappa := epicyclicFrequency(model, config.Potentials, G, r, angle)
+			if kappa > 0 {
+				sigma := toomreStellarFactor * G * surfaceDensity(model, r) * config.ToomreQ / kappa
+				sigmaPhi := sigma * kappa / (2 * circularVelocity / r)
+
+				velocity = velocity.Add(radial.Multiply(radialNoise * sigma))
+				velocity = velocity.Add(tangential.Multiply(tangentialNoise * sigmaPhi))
+			}
+		}
+
+		stars[i] = NewStar2D(radial.Multiply(r), velocity, totalMass/float64(config.Stars))
+	}
+
+	// the masses are drawn from a stream of their own seeded by the generator after all the positions are drawn, so
+	// they are independent of the positions and the positions don't depend on the IMF
+	if config.IMF != nil {
+		AssignMasses(stars, config.IMF, random.Int63(), totalMass)
+	}
+
+	return stars, nil
+}
+
+// enclosedMassRadius returns the radius enclosing the given mass by bisecting the interval [0, maxRadius]
+func enclosedMassRadius(model GalaxyModel, mass float64, maxRadius float64) float64 {
+	low, high := 0.0, maxRadius
+	for i := 0; i < 100 && high-low > 1e-12*maxRadius; i++ {
+		middle := (low + high) / 2
+		if model.EnclosedMass(middle) < mass {
+			low = middle
+		} else {
+			high = middle
+		}
+	}
+	return (low + high) / 2
+}
+
+// galaxyCircularVelocity returns the circular velocity at the radius r in the direction of the given angle
+func galaxyCircularVelocity(model GalaxyModel, potentials []ExternalPotential, G float64, r float64, angle float64) float64 {
+	if r <= 0 {
+		return 0
+	}
+
+	squared := G * model.EnclosedMass(r) / r
+	if disk, ok := model.(DiskModel); ok {
+		squared = r * disk.RadialAcceleration(G, r)
+	}
+
+	direction := NewVec2Polar(1, angle)
+	for _, potential := range potentials {
+		acceleration := potential.Acceleration(direction.Multiply(r), 0)
+		squared -= r * (acceleration.Dot(direction))
+	}
+
+	return math.Sqrt(math.Max(squared, 0))
+}
+
+// epicyclicFrequency returns kappa = sqrt(2*(v/r)^2 * (1 + dln(v)/dln(r))) at the radius r
+func epicyclicFrequency(model GalaxyModel, potentials []ExternalPotential, G float64, r float64, angle float64) float64 {
+	step := 1e-4 * r
+	velocity := galaxyCircularVelocity(model, potentials, G, r, angle)
+	outer := galaxyCircularVelocity(model, potentials, G, r+step, angle)
+	inner := galaxyCircularVelocity(model, potentials, G, r-step, angle)
+
+	logSlope := (math.Log(outer) - math.Log(inner)) / (math.Log(r+step) - math.Log(r-step))
+	return math.Sqrt(2 * velocity * velocity / (r * r) * (1 + logSlope))
+}
+
+// surfaceDensity returns the surface density dM/dr / (2*pi*r) of the model flattened into the plane at the radius r
+func surfaceDensity(model GalaxyModel, r float64) float64 {
+	step := 1e-4 * r
+	return (model.EnclosedMass(r+step) - model.EnclosedMass(r-step)) / (2 * step) / (2 * math.Pi * r)
+}
+
+// scaledBesselI returns the modified bessel function of the first kind I_n(x) * exp(-x) for x >= 0 using the
+// trapezoidal rule on I_n(x) = 1/pi * integral from 0 to pi of exp(x*cos(t)) * cos(n*t) dt. The integrand is
+// periodic, so the trapezoidal rule converges exponentially.
+func scaledBesselI(n int, x float64) float64 {
+	steps := 64 + int(8*math.Sqrt(x))
+	h := math.Pi / float64(steps)
+
+	sum := 0.0
+	for i := 0; i <= steps; i++ {
+		t := float64(i) * h
+		term := math.Exp(x*(math.Cos(t)-1)) * math.Cos(float64(n)*t)
+		if i == 0 || i == steps {
+			term /= 2
+		}
+		sum += term
+	}
+	return sum * h / math.Pi
+}
+
+// scaledBesselK returns the modified bessel function of the second kind K_n(x) * exp(x) for x > 0 using the
+// trapezoidal rule on K_n(x) = integral from 0 to infinity of exp(-x*cosh(t)) * cosh(n*t) dt. The integrand decays
+// double exponentially, so the trapezoidal rule converges exponentially.
+func scaledBesselK(n int, x float64) float64 {
+	const h = 0.05
+
+	// the integrand is 1 at t = 0, the first point only counts half. cosh(t) overflows beyond t = 710.
+	sum := 0.5
+	for t := h; t < 700; t += h {
+		term := math.Exp(-x*(math.Cosh(t)-1)) * math.Cosh(float64(n)*t)
+		sum += term
+		if term < 1e-17*sum {
+			break
+		}
+	}
+	return sum * h
+}
+
+// ellipticKMinusE returns K(k) - E(k) using the complete elliptic integrals of the first and the second kind of the
+// modulus k, calculated using the arithmetic-geometric mean. The difference is calculated directly, as subtracting
+// E(k) from K(k) loses all the digits for small k.
+func ellipticKMinusE(k float64) float64 {
+	if k >= 1 {
+		return math.Inf(1)
+	}
+
+	// K(k) = pi / (2*AGM(1, sqrt(1-k^2))) and K(k) - E(k) = K(k) * sum of 2^(n-1) * c_n^2
+	a, b, c := 1.0, math.Sqrt(1-k*k), k
+	sum := c * c / 2
+	power := 1.0
+	for i := 0; i < 64 && c > 1e-15*a; i++ {
+		a, b, c = (a+b)/2, math.Sqrt(a*b), (a-b)/2
+		sum += power * c * c
+		power *= 2
+	}
+	return math.Pi / (2 * a) * sum
+}
@@ -0,0 +1,303 @@
+// generators_test.go provides tests for generators.go
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// GenerateGalaxy generates the stars of a galaxy in equilibrium
+func ExampleGenerateGalaxy() {
+	model := ExponentialDiskModel{Mass: 5e10, ScaleLength: 3}
+	config := GeneratorConfig{Stars: 1000, Seed: 1, Units: GalacticUnits, ToomreQ: 1.5}
+
+	stars, err := GenerateGalaxy(model, config)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	totalMass := 0.0
+	for _, star := range stars {
+		totalMass += star.M
+	}
+	fmt.Printf("%v stars of %.4g solar masses\n", len(stars), totalMass)
+	// Output:
+	// 1000 stars of 4.998e+10 solar masses
+}
+
+func TestGenerateGalaxy(t *testing.T) {
+	tests := []struct {
+		name  string
+		model GalaxyModel
+	}{
+		{
+			name:  "plummer sphere",
+			model: PlummerModel{Mass: 1, ScaleLength: 1},
+		},
+		{
+			name:  "hernquist bulge",
+			model: HernquistModel{Mass: 1, ScaleLength: 0.5},
+		},
+		{
+			name:  "exponential disk",
+			model: ExponentialDiskModel{Mass: 1, ScaleLength: 2},
+		},
+		{
+			name:  "kuzmin disk",
+			model: KuzminDiskModel{Mass: 1, ScaleLength: 2},
+		},
+		{
+			name:  "uniform disk",
+			model: UniformDiskModel{Mass: 1, Radius: 5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := GeneratorConfig{Stars: 20000, Seed: 2, Units: NBodyUnits}
+			stars, err := GenerateGalaxy(tt.model, config)
+			if err != nil {
+				t.Fatalf("GenerateGalaxy() error = %v", err)
+			}
+			maxRadius := 10 * tt.model.ScaleRadius()
+
+			// the stars follow the enclosed mass of the model
+			for _, radius := range []float64{0.5, 1, 2} {
+				radius *= tt.model.ScaleRadius()
+
+				inside := 0
+				for _, star := range stars {
+					if math.Hypot(star.C.X, star.C.Y) < radius {
+						inside++
+					}
+				}
+
+				want := tt.model.EnclosedMass(radius) / tt.model.EnclosedMass(maxRadius)
+				got := float64(inside) / float64(len(stars))
+				if math.Abs(got-want) > 4*math.Sqrt(want*(1-want)/float64(len(stars)))+1e-9 {
+					t.Errorf("GenerateGalaxy() fraction inside of %v = %v, want %v", radius, got, want)
+				}
+			}
+
+			// a cold galaxy rotates counterclockwise using the circular velocity, disks using their own radial
+			// acceleration
+			for _, star := range stars[:100] {
+				r := math.Hypot(star.C.X, star.C.Y)
+				want := math.Sqrt(config.Units.G() * tt.model.EnclosedMass(r) / r)
+				if disk, ok := tt.model.(DiskModel); ok {
+					want = math.Sqrt(r * disk.RadialAcceleration(config.Units.G(), r))
+				}
+				if math.Abs(math.Hypot(star.V.X, star.V.Y)-want) > 1e-9*want ||
+					math.Abs(star.C.X*star.V.X+star.C.Y*star.V.Y) > 1e-9*r*want ||
+					star.C.X*star.V.Y-star.C.Y*star.V.X < 0 {
+					t.Errorf("GenerateGalaxy() velocity = %v at %v, want %v counterclockwise", star.V, star.C, want)
+				}
+			}
+
+			// generating the stars using the same seed results in the same stars
+			again, _ := GenerateGalaxy(tt.model, config)
+			if reflect.DeepEqual(stars, again) == false {
+				t.Errorf("GenerateGalaxy() generated different stars using the same seed")
+			}
+			config.Seed++
+			other, _ := GenerateGalaxy(tt.model, config)
+			if reflect.DeepEqual(stars, other) == true {
+				t.Errorf("GenerateGalaxy() generated the same stars using different seeds")
+			}
+		})
+	}
+}
+
+func TestDiskModel_RadialAcceleration(t *testing.T) {
+	G := NBodyUnits.G()
+
+	// I0, K0, I1 and K1 at 1 and 5 taken from tables, they define the velocity of the exponential disk at 2 and 10
+	// scale lengths
+	freeman := func(y float64, i0, k0, i1, k1 float64) float64 {
+		return 2 * G * 3 / 1.5 * y * y * (i0*k0 - i1*k1) / (2 * 1.5 * y)
+	}
+
+	tests := []struct {
+		name  string
+		model DiskModel
+		r     float64
+		want  float64
+	}{
+		{
+			name:  "kuzmin disk at the scale length",
+			model: KuzminDiskModel{Mass: 3, ScaleLength: 2},
+			r:     2,
+			want:  -Kuzmin{Mass: 3, ScaleLength: 2, Units: NBodyUnits}.Acceleration(Vec2{2, 0}, 0).X,
+		},
+		{
+			name:  "kuzmin disk far outside",
+			model: KuzminDiskModel{Mass: 3, ScaleLength: 2},
+			r:     50,
+			want:  -Kuzmin{Mass: 3, ScaleLength: 2, Units: NBodyUnits}.Acceleration(Vec2{0, 50}, 0).Y,
+		},
+		{
+			name:  "exponential disk at two scale lengths",
+			model: ExponentialDiskModel{Mass: 3, ScaleLength: 1.5},
+			r:     3,
+			want:  freeman(1, 1.266065877752008, 0.4210244382407083, 0.5651591039924851, 0.6019072301972346),
+		},
+		{
+			name:  "exponential disk at ten scale lengths",
+			model: ExponentialDiskModel{Mass: 3, ScaleLength: 1.5},
+			r:     15,
+			want:  freeman(5, 27.23987182360445, 0.003691098334042594, 24.33564214245053, 0.004044613445452164),
+		},
+		{
+			name:  "uniform disk close to the center",
+			model: UniformDiskModel{Mass: 3, Radius: 2},
+			r:     1e-3,
+			want:  math.Pi * G * 3 / (math.Pi * 4) * 1e-3 / 2 * (1 + 3.0/8*0.25e-6),
+		},
+		{
+			name:  "uniform disk far outside",
+			model: UniformDiskModel{Mass: 3, Radius: 2},
+			r:     1000,
+			want:  G * 3 / (1000 * 1000) * (1 + 3.0/8*4/(1000*1000)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.model.RadialAcceleration(G, tt.r); math.Abs(got-tt.want) > 1e-9*tt.want {
+				t.Errorf("%T.RadialAcceleration() = %v, want %v", tt.model, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateGalaxy_toomreQ(t *testing.T) {
+	model := ExponentialDiskModel{Mass: 5e10, ScaleLength: 3}
+	halo := Isothermal{CircularVelocity: 200, CoreRadius: 2}
+	config := GeneratorConfig{Stars: 50000, Seed: 3, Units: GalacticUnits, ToomreQ: 1.2, Potentials: []ExternalPotential{halo}}
+
+	stars, err := GenerateGalaxy(model, config)
+	if err != nil {
+		t.Fatalf("GenerateGalaxy() error = %v", err)
+	}
+
+	// measure the radial velocity dispersion in an annulus around two scale lengths
+	var sum, sumSquared float64
+	count := 0
+	for _, star := range stars {
+		r := math.Hypot(star.C.X, star.C.Y)
+		if r < 5.9 || r > 6.1 {
+			continue
+		}
+		radialVelocity := (star.C.X*star.V.X + star.C.Y*star.V.Y) / r
+		sum += radialVelocity
+		sumSquared += radialVelocity * radialVelocity
+		count++
+	}
+	mean := sum / float64(count)
+	sigma := math.Sqrt(sumSquared/float64(count) - mean*mean)
+
+	// calculate the Toomre Q using the measured dispersion
+	G := GalacticUnits.G()
+	kappa := epicyclicFrequency(model, config.Potentials, G, 6, 0)
+	q := sigma * kappa / (toomreStellarFactor * G * surfaceDensity(model, 6))
+	if math.Abs(q-config.ToomreQ) > 0.1*config.ToomreQ {
+		t.Errorf("GenerateGalaxy() Toomre Q = %v using %v stars, want %v", q, count, config.ToomreQ)
+	}
+
+	// the halo raises the circular velocity
+	if v := galaxyCircularVelocity(model, config.Potentials, G, 20, 0); v < 200 {
+		t.Errorf("galaxyCircularVelocity() = %v, want more than the velocity of the halo", v)
+	}
+}
+
+func TestGenerateGalaxy_error(t *testing.T) {
+	tests := []struct {
+		name   string
+		model  GalaxyModel
+		config GeneratorConfig
+	}{
+		{
+			name:   "negative amount of stars",
+			model:  PlummerModel{Mass: 1, ScaleLength: 1},
+			config: GeneratorConfig{Stars: -1},
+		},
+		{
+			name:   "model without a scale radius",
+			model:  PlummerModel{Mass: 1},
+			config: GeneratorConfig{Stars: 10},
+		},
+		{
+			name:   "model without mass",
+			model:  HernquistModel{ScaleLength: 1},
+			config: GeneratorConfig{Stars: 10},
+		},
+		{
+			name:   "negative Toomre Q",
+			model:  KuzminDiskModel{Mass: 1, ScaleLength: 1},
+			config: GeneratorConfig{Stars: 10, ToomreQ: -1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := GenerateGalaxy(tt.model, tt.config); errors.Is(err, ErrInvalidModel) == false {
+				t.Errorf("GenerateGalaxy() error = %v, want %v", err, ErrInvalidModel)
+			}
+		})
+	}
+}
+
+func TestGenerateGalaxy_imf(t *testing.T) {
+	imf, _ := NewKroupaIMF(0.08, 100)
+	model := PlummerModel{Mass: 1e9, ScaleLength: 1}
+	config := GeneratorConfig{Stars: 1000, Seed: 36, Units: GalacticUnits, IMF: imf}
+
+	stars, err := GenerateGalaxy(model, config)
+	if err != nil {
+		t.Fatalf("GenerateGalaxy() error = %v", err)
+	}
+
+	// the masses differ, but they still add up to the mass of the model
+	totalMass, minMass, maxMass := 0.0, math.Inf(1), 0.0
+	for _, star := range stars {
+		totalMass += star.M
+		minMass = math.Min(minMass, star.M)
+		maxMass = math.Max(maxMass, star.M)
+	}
+	if want := model.EnclosedMass(10); math.Abs(totalMass-want) > 1e-9*want || maxMass < 10*minMass {
+		t.Errorf("GenerateGalaxy() total mass = %v, masses between %v and %v, want %v", totalMass, minMass, maxMass, want)
+	}
+
+	// the masses aren't drawn using the seed of the positions, which would correlate the mass of a star with its
+	// position
+	masses := SampleMasses(imf, len(stars), config.Seed, model.EnclosedMass(10))
+	for i, star := range stars {
+		if star.M == masses[i] {
+			t.Errorf("GenerateGalaxy() mass = %v of star %v, drawn using the seed of the positions", star.M, i)
+			break
+		}
+	}
+
+	// the positions don't depend on the masses
+	config.IMF = nil
+	equalMass, _ := GenerateGalaxy(model, config)
+	if stars[10].C != equalMass[10].C || stars[10].V != equalMass[10].V {
+		t.Errorf("GenerateGalaxy() star = %v using an IMF, want %v", stars[10], equalMass[10])
+	}
+}
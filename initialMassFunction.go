@@ -0,0 +1,321 @@
+// initialMassFunction.go samples the masses of stars from initial mass functions
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+)
+
+// ErrInvalidIMF is returned when an initial mass function can't be created using the given masses and slopes
+var ErrInvalidIMF = errors.New("invalid initial mass function")
+
+// Slopes and scales of the standard initial mass functions, dN/dm is proportional to m^-slope
+const (
+	salpeterSlope = 2.35
+
+	chabrierCharacteristicMass = 0.079 // solar masses
+	chabrierLogWidth           = 0.69  // width of the log-normal part in dex
+	chabrierSlope              = 2.3   // slope above one solar mass
+)
+
+// kroupaMasses and kroupaSlopes define the broken power law of the Kroupa initial mass function
+var (
+	kroupaMasses = []float64{0, 0.08, 0.5, math.Inf(1)}
+	kroupaSlopes = []float64{0.3, 1.3, 2.3}
+)
+
+// IMF is an initial mass function defining the distribution of the masses of stars. The masses are measured in
+// solar masses and lie between the lower and the upper cutoff of the function.
+type IMF interface {
+
+	// Density returns the probability density dN/dm of the mass m, it is normalized to 1 between the cutoffs
+	Density(m float64) float64
+
+	// Sample draws a random mass using the given random number generator
+	Sample(random *rand.Rand) float64
+
+	// MeanMass returns the mean mass of the stars
+	MeanMass() float64
+}
+
+// PowerLawIMF is an initial mass function made of power laws dN/dm ~ m^-slope joined continuously at the masses
+// between them
+type PowerLawIMF struct {
+	masses  []float64 // masses bounding the segments, the first and the last one are the cutoffs
+	slopes  []float64 // slopes of the segments
+	scales  []float64 // factor of the power law of every segment
+	weights []float64 // cumulative probability at the end of every segment
+}
+
+// NewPowerLawIMF returns a new initial mass function using the given power laws. The masses bound the segments, so
+// there has to be one slope less than masses. The first mass is the lower cutoff, the last mass is the upper
+// cutoff. The masses have to be positive, finite and increasing.
+func NewPowerLawIMF(masses []float64, slopes []float64) (*PowerLawIMF, error) {
+	if len(slopes) == 0 || len(masses) != len(slopes)+1 {
+		return nil, ErrInvalidIMF
+	}
+	for i, mass := range masses {
+		if mass <= 0 || math.IsInf(mass, 0) || math.IsNaN(mass) || (i > 0 && mass <= masses[i-1]) {
+			return nil, ErrInvalidIMF
+		}
+	}
+	for _, slope := range slopes {
+		if math.IsInf(slope, 0) || math.IsNaN(slope) {
+			return nil, ErrInvalidIMF
+		}
+	}
+
+	imf := &PowerLawIMF{
+		masses:  append([]float64(nil), masses...),
+		slopes:  append([]float64(nil), slopes...),
+		scales:  make([]float64, len(slopes)),
+		weights: make([]float64, len(slopes)),
+	}
+
+	// join the segments continuously and sum up their integrals
+	total := 0.0
+	for i, slope := range imf.slopes {
+		imf.scales[i] = 1
+		if i > 0 {
+			imf.scales[i] = imf.scales[i-1] * math.Pow(masses[i], slope-imf.slopes[i-1])
+		}
+		total += imf.scales[i] * powerLawIntegral(masses[i], masses[i+1], slope)
+		imf.weights[i] = total
+	}
+
+	// normalize the function
+	for i := range imf.slopes {
+		imf.scales[i] /= total
+		imf.weights[i] /= total
+	}
+	imf.weights[len(imf.weights)-1] = 1
+
+	return imf, nil
+}
+
+// NewSalpeterIMF returns the Salpeter initial mass function dN/dm ~ m^-2.35 between the given cutoffs
+func NewSalpeterIMF(minMass float64, maxMass float64) (*PowerLawIMF, error) {
+	return NewPowerLawIMF([]float64{minMass, maxMass}, []float64{salpeterSlope})
+}
+
+// NewKroupaIMF returns the Kroupa initial mass function between the given cutoffs. It is a broken power law using
+// the slope 0.3 below 0.08 solar masses, 1.3 up to 0.5 solar masses and 2.3 above.
+func NewKroupaIMF(minMass float64, maxMass float64) (*PowerLawIMF, error) {
+	if !(minMass < maxMass) {
+		return nil, ErrInvalidIMF
+	}
+
+	masses := []float64{minMass}
+	var slopes []float64
+	for i, slope := range kroupaSlopes {
+		if kroupaMasses[i+1] > minMass && kroupaMasses[i] < maxMass {
+			masses = append(masses, math.Min(kroupaMasses[i+1], maxMass))
+			slopes = append(slopes, slope)
+		}
+	}
+	return NewPowerLawIMF(masses, slopes)
+}
+
+// Density returns the probability density dN/dm of the mass m, it is zero outside of the cutoffs
+func (imf *PowerLawIMF) Density(m float64) float64 {
+	for i, slope := range imf.slopes {
+		if m >= imf.masses[i] && m <= imf.masses[i+1] {
+			return imf.scales[i] * math.Pow(m, -slope)
+		}
+	}
+	return 0
+}
+
+// Sample draws a random mass by choosing a segment using its share of the stars and inverting its cumulative
+// distribution
+func (imf *PowerLawIMF) Sample(random *rand.Rand) float64 {
+	u := random.Float64()
+
+	previous := 0.0
+	for i, weight := range imf.weights {
+		if u < weight || i == len(imf.weights)-1 {
+			fraction := math.Min(1, (u-previous)/(weight-previous))
+			mass := invertPowerLaw(imf.masses[i], imf.masses[i+1], imf.slopes[i], fraction)
+			return math.Max(imf.masses[i], math.Min(imf.masses[i+1], mass))
+		}
+		previous = weight
+	}
+	return imf.masses[len(imf.masses)-1]
+}
+
+// MeanMass returns the mean mass of the stars
+func (imf *PowerLawIMF) MeanMass() float64 {
+	mean := 0.0
+	for i, slope := range imf.slopes {
+		mean += imf.scales[i] * powerLawIntegral(imf.masses[i], imf.masses[i+1], slope-1)
+	}
+	return mean
+}
+
+// ChabrierIMF is the Chabrier (2003) initial mass function. Below one solar mass it is log-normal in the mass using
+// the characteristic mass 0.079 solar masses and the width 0.69 dex, above it is a power law dN/dm ~ m^-2.3.
+type ChabrierIMF struct {
+	minMass float64
+	maxMass float64
+
+	logNormalMin    float64 // cumulative normal distribution at the lower cutoff of the log-normal part
+	logNormalMax    float64 // cumulative normal distribution at the upper cutoff of the log-normal part
+	logNormalWeight float64 // share of the stars in the log-normal part
+	scale           float64 // factor of dN/dlog10(m) of the log-normal part
+	powerLaw        *PowerLawIMF
+}
+
+// NewChabrierIMF returns the Chabrier initial mass function between the given cutoffs
+func NewChabrierIMF(minMass float64, maxMass float64) (*ChabrierIMF, error) {
+	if minMass <= 0 || !(minMass < maxMass) || math.IsInf(maxMass, 0) {
+		return nil, ErrInvalidIMF
+	}
+	imf := &ChabrierIMF{minMass: minMass, maxMass: maxMass}
+
+	// amount of stars in the log-normal part, using dN/dlog10(m) = exp(-(log10(m)-log10(mc))^2/(2 sigma^2))
+	var logNormal float64
+	if minMass < 1 {
+		imf.logNormalMin = chabrierNormal(minMass)
+		imf.logNormalMax = chabrierNormal(math.Min(maxMass, 1))
+		logNormal = chabrierLogWidth * math.Sqrt(2*math.Pi) * (imf.logNormalMax - imf.logNormalMin)
+	}
+
+	// amount of stars in the power law part, joined continuously at one solar mass
+	var powerLaw float64
+	if maxMass > 1 {
+		imf.powerLaw, _ = NewPowerLawIMF([]float64{math.Max(minMass, 1), maxMass}, []float64{chabrierSlope})
+		logOffset := math.Log10(chabrierCharacteristicMass)
+		height := math.Exp(-logOffset * logOffset / (2 * chabrierLogWidth * chabrierLogWidth))
+		powerLaw = height / math.Ln10 * powerLawIntegral(math.Max(minMass, 1), maxMass, chabrierSlope)
+	}
+
+	imf.logNormalWeight = logNormal / (logNormal + powerLaw)
+	imf.scale = 1 / (logNormal + powerLaw)
+	return imf, nil
+}
+
+// Density returns the probability density dN/dm of the mass m, it is zero outside of the cutoffs
+func (imf *ChabrierIMF) Density(m float64) float64 {
+	if m < imf.minMass || m > imf.maxMass {
+		return 0
+	}
+	if m > 1 {
+		return (1 - imf.logNormalWeight) * imf.powerLaw.Density(m)
+	}
+	logOffset := math.Log10(m / chabrierCharacteristicMass)
+	return imf.scale * math.Exp(-logOffset*logOffset/(2*chabrierLogWidth*chabrierLogWidth)) / (m * math.Ln10)
+}
+
+// Sample draws a random mass by choosing the log-normal or the power law part using their share of the stars and
+// inverting the cumulative distribution of the chosen part
+func (imf *ChabrierIMF) Sample(random *rand.Rand) float64 {
+	u := random.Float64()
+	if u >= imf.logNormalWeight {
+		return imf.powerLaw.Sample(random)
+	}
+
+	p := imf.logNormalMin + random.Float64()*(imf.logNormalMax-imf.logNormalMin)
+	logMass := math.Log10(chabrierCharacteristicMass) + chabrierLogWidth*math.Sqrt2*math.Erfinv(2*p-1)
+	return math.Max(imf.minMass, math.Min(math.Min(imf.maxMass, 1), math.Pow(10, logMass)))
+}
+
+// MeanMass returns the mean mass of the stars
+func (imf *ChabrierIMF) MeanMass() float64 {
+	mean := 0.0
+	if imf.powerLaw != nil {
+		mean += (1 - imf.logNormalWeight) * imf.powerLaw.MeanMass()
+	}
+
+	// the mass weighted log-normal part is a log-normal shifted by sigma^2 ln(10) dex
+	if imf.logNormalWeight > 0 {
+		shift := chabrierLogWidth * chabrierLogWidth * math.Ln10
+		lower := chabrierNormal(imf.minMass / math.Pow(10, shift))
+		upper := chabrierNormal(math.Min(imf.maxMass, 1) / math.Pow(10, shift))
+		logMean := math.Log10(chabrierCharacteristicMass) + shift/2
+		mean += imf.scale * chabrierLogWidth * math.Sqrt(2*math.Pi) * math.Pow(10, logMean) * (upper - lower)
+	}
+	return mean
+}
+
+// chabrierNormal returns the cumulative normal distribution of log10(m) of the log-normal part of the Chabrier
+// initial mass function
+func chabrierNormal(m float64) float64 {
+	return 0.5 * (1 + math.Erf(math.Log10(m/chabrierCharacteristicMass)/(chabrierLogWidth*math.Sqrt2)))
+}
+
+// powerLawIntegral returns the integral of m^-slope from a to b
+func powerLawIntegral(a float64, b float64, slope float64) float64 {
+	if slope == 1 {
+		return math.Log(b / a)
+	}
+	return (math.Pow(b, 1-slope) - math.Pow(a, 1-slope)) / (1 - slope)
+}
+
+// invertPowerLaw returns the mass between a and b below which the given fraction of the integral of m^-slope lies
+func invertPowerLaw(a float64, b float64, slope float64, fraction float64) float64 {
+	if slope == 1 {
+		return a * math.Pow(b/a, fraction)
+	}
+	lower := math.Pow(a, 1-slope)
+	return math.Pow(lower+fraction*(math.Pow(b, 1-slope)-lower), 1/(1-slope))
+}
+
+// SampleMasses draws the given amount of masses from the initial mass function using the given seed. If the total
+// mass is positive, the masses are scaled so that they add up to it, which also converts them from solar masses
+// into the unit of the total mass.
+func SampleMasses(imf IMF, n int, seed int64, totalMass float64) []float64 {
+	random := rand.New(rand.NewSource(seed))
+
+	masses := make([]float64, n)
+	sum := 0.0
+	for i := range masses {
+		masses[i] = imf.Sample(random)
+		sum += masses[i]
+	}
+
+	if totalMass > 0 && sum > 0 {
+		for i := range masses {
+			masses[i] *= totalMass / sum
+		}
+	}
+	return masses
+}
+
+// SampleMassesUpTo draws masses from the initial mass function using the given seed until they add up to the total
+// mass in solar masses. The last mass is drawn as well, so the sum exceeds the total mass by less than the upper
+// cutoff of the initial mass function.
+func SampleMassesUpTo(imf IMF, totalMass float64, seed int64) []float64 {
+	random := rand.New(rand.NewSource(seed))
+
+	var masses []float64
+	for sum := 0.0; sum < totalMass; {
+		mass := imf.Sample(random)
+		masses = append(masses, mass)
+		sum += mass
+	}
+	return masses
+}
+
+// AssignMasses replaces the masses of the given stars using masses drawn from the initial mass function, see
+// SampleMasses
+func AssignMasses(stars []Star2D, imf IMF, seed int64, totalMass float64) {
+	for i, mass := range SampleMasses(imf, len(stars), seed, totalMass) {
+		stars[i].M = mass
+	}
+}
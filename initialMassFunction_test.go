@@ -0,0 +1,210 @@
+// initialMassFunction_test.go provides tests for initialMassFunction.go
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// SampleMasses draws the masses of stars from an initial mass function
+func ExampleSampleMasses() {
+	imf, err := NewKroupaIMF(0.08, 100)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	masses := SampleMasses(imf, 5, 1, 0)
+	fmt.Printf("%.3f\n", masses)
+	// Output:
+	// [0.313 1.455 0.372 0.203 0.196]
+}
+
+// integrateIMF integrates f(m) times the density of the initial mass function from a to b using the trapezoidal
+// rule on a logarithmic grid
+func integrateIMF(imf IMF, a float64, b float64, f func(m float64) float64) float64 {
+	const steps = 200000
+	sum := 0.0
+	logStep := math.Log(b/a) / steps
+	for i := 0; i <= steps; i++ {
+		m := a * math.Exp(float64(i)*logStep)
+		weight := 1.0
+		if i == 0 || i == steps {
+			weight = 0.5
+		}
+		sum += weight * f(m) * imf.Density(m) * m * logStep
+	}
+	return sum
+}
+
+func TestIMF(t *testing.T) {
+	salpeter, _ := NewSalpeterIMF(0.1, 100)
+	kroupa, _ := NewKroupaIMF(0.01, 150)
+	kroupaHigh, _ := NewKroupaIMF(0.2, 50)
+	chabrier, _ := NewChabrierIMF(0.08, 100)
+	chabrierLow, _ := NewChabrierIMF(0.01, 0.8)
+	custom, _ := NewPowerLawIMF([]float64{0.5, 1, 2, 10}, []float64{-1, 1, 3})
+
+	tests := []struct {
+		name    string
+		imf     IMF
+		minMass float64
+		maxMass float64
+		breaks  []float64
+	}{
+		{name: "salpeter", imf: salpeter, minMass: 0.1, maxMass: 100},
+		{name: "kroupa", imf: kroupa, minMass: 0.01, maxMass: 150, breaks: []float64{0.08, 0.5}},
+		{name: "kroupa above the first break", imf: kroupaHigh, minMass: 0.2, maxMass: 50, breaks: []float64{0.5}},
+		{name: "chabrier", imf: chabrier, minMass: 0.08, maxMass: 100, breaks: []float64{1}},
+		{name: "chabrier below one solar mass", imf: chabrierLow, minMass: 0.01, maxMass: 0.8},
+		{name: "piecewise power law", imf: custom, minMass: 0.5, maxMass: 10, breaks: []float64{1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			// the density is normalized, continuous and zero outside of the cutoffs
+			if total := integrateIMF(tt.imf, tt.minMass, tt.maxMass, func(float64) float64 { return 1 }); math.Abs(total-1) > 1e-6 {
+				t.Errorf("Density() integrates to %v, want 1", total)
+			}
+			for _, m := range tt.breaks {
+				below, above := tt.imf.Density(m*(1-1e-9)), tt.imf.Density(m*(1+1e-9))
+				if math.Abs(below-above) > 1e-6*below {
+					t.Errorf("Density() = %v below and %v above %v, want it continuous", below, above, m)
+				}
+			}
+			if tt.imf.Density(tt.minMass/2) != 0 || tt.imf.Density(tt.maxMass*2) != 0 {
+				t.Errorf("Density() is not zero outside of the cutoffs")
+			}
+
+			// the mean mass is the first moment of the density
+			mean := tt.imf.MeanMass()
+			if want := integrateIMF(tt.imf, tt.minMass, tt.maxMass, func(m float64) float64 { return m }); math.Abs(mean-want) > 1e-5*want {
+				t.Errorf("MeanMass() = %v, want %v", mean, want)
+			}
+
+			// the samples follow the density
+			masses := SampleMasses(tt.imf, 200000, 31, 0)
+			var sum, sumSquared float64
+			for _, mass := range masses {
+				if mass < tt.minMass || mass > tt.maxMass {
+					t.Fatalf("Sample() = %v, want between %v and %v", mass, tt.minMass, tt.maxMass)
+				}
+				sum += mass
+				sumSquared += mass * mass
+			}
+			sampleMean := sum / float64(len(masses))
+			standardError := math.Sqrt((sumSquared/float64(len(masses)) - sampleMean*sampleMean) / float64(len(masses)))
+			if math.Abs(sampleMean-mean) > 5*standardError {
+				t.Errorf("Sample() mean = %v, want %v +- %v", sampleMean, mean, 5*standardError)
+			}
+
+			for _, quantile := range []float64{0.25, 0.5, 0.75} {
+				m := tt.minMass * math.Pow(tt.maxMass/tt.minMass, quantile)
+				want := integrateIMF(tt.imf, tt.minMass, m, func(float64) float64 { return 1 })
+
+				below := 0
+				for _, mass := range masses {
+					if mass < m {
+						below++
+					}
+				}
+				got := float64(below) / float64(len(masses))
+				if math.Abs(got-want) > 5*math.Sqrt(want*(1-want)/float64(len(masses)))+1e-6 {
+					t.Errorf("Sample() fraction below %v = %v, want %v", m, got, want)
+				}
+			}
+
+			// the same seed results in the same masses
+			if reflect.DeepEqual(masses[:100], SampleMasses(tt.imf, 100, 31, 0)) == false {
+				t.Errorf("SampleMasses() returned different masses using the same seed")
+			}
+		})
+	}
+}
+
+func TestSampleMasses_totalMass(t *testing.T) {
+	imf, _ := NewSalpeterIMF(0.5, 50)
+
+	// the masses are scaled to the total mass
+	masses := SampleMasses(imf, 1000, 32, 3)
+	sum := 0.0
+	for _, mass := range masses {
+		sum += mass
+	}
+	if math.Abs(sum-3) > 1e-12 {
+		t.Errorf("SampleMasses() total mass = %v, want 3", sum)
+	}
+
+	// masses are drawn until they reach the total mass
+	masses = SampleMassesUpTo(imf, 1e4, 33)
+	sum = 0
+	for _, mass := range masses {
+		sum += mass
+	}
+	if sum < 1e4 || sum-masses[len(masses)-1] >= 1e4 {
+		t.Errorf("SampleMassesUpTo() total mass = %v using %v masses, want just above %v", sum, len(masses), 1e4)
+	}
+
+	// the masses of stars are replaced
+	stars := []Star2D{NewStar2D(Vec2{1, 2}, Vec2{3, 4}, 1), NewStar2D(Vec2{5, 6}, Vec2{7, 8}, 1)}
+	AssignMasses(stars, imf, 34, 10)
+	if stars[0].M+stars[1].M != 10 || stars[0].M == stars[1].M || stars[1].C != (Vec2{5, 6}) {
+		t.Errorf("AssignMasses() stars = %v, want masses adding up to 10", stars)
+	}
+
+	// the upper cutoff of the IMF is never exceeded when sampling
+	random := rand.New(rand.NewSource(35))
+	for i := 0; i < 1000; i++ {
+		if mass := imf.Sample(random); mass > 50 {
+			t.Fatalf("Sample() = %v, want at most 50", mass)
+		}
+	}
+}
+
+func TestNewPowerLawIMF_error(t *testing.T) {
+	tests := []struct {
+		name   string
+		masses []float64
+		slopes []float64
+	}{
+		{name: "no slopes", masses: []float64{1}},
+		{name: "too many slopes", masses: []float64{1, 2}, slopes: []float64{1, 2}},
+		{name: "decreasing masses", masses: []float64{1, 0.5}, slopes: []float64{2}},
+		{name: "massless cutoff", masses: []float64{0, 1}, slopes: []float64{2}},
+		{name: "infinite cutoff", masses: []float64{1, math.Inf(1)}, slopes: []float64{2}},
+		{name: "invalid slope", masses: []float64{1, 2}, slopes: []float64{math.NaN()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewPowerLawIMF(tt.masses, tt.slopes); errors.Is(err, ErrInvalidIMF) == false {
+				t.Errorf("NewPowerLawIMF() error = %v, want %v", err, ErrInvalidIMF)
+			}
+		})
+	}
+
+	if _, err := NewKroupaIMF(1, 0.5); errors.Is(err, ErrInvalidIMF) == false {
+		t.Errorf("NewKroupaIMF() error = %v, want %v", err, ErrInvalidIMF)
+	}
+	if _, err := NewChabrierIMF(0, 100); errors.Is(err, ErrInvalidIMF) == false {
+		t.Errorf("NewChabrierIMF() error = %v, want %v", err, ErrInvalidIMF)
+	}
+}
@@ -0,0 +1,283 @@
+// integrator.go advances stars in time using the accelerations acting on them
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+import "math"
+
+// AccelerationProvider calculates the accelerations acting on stars
+type AccelerationProvider interface {
+
+	// Accelerations returns the acceleration of every given star at the time t
+	Accelerations(stars []Star2D, t float64) ([]Vec2, error)
+}
+
+// AccelerationFunc is a function used as an AccelerationProvider
+type AccelerationFunc func(stars []Star2D, t float64) ([]Vec2, error)
+
+// Accelerations calls the function itself
+func (f AccelerationFunc) Accelerations(stars []Star2D, t float64) ([]Vec2, error) {
+	return f(stars, t)
+}
+
+// TreeAccelerations calculates the accelerations by building a tree out of the stars and walking it for every star
+// using ComputeAccelerations. The tree is built again every time, as the stars move in between two calls.
+type TreeAccelerations struct {
+	Theta   float64       // Opening angle used by the opening criterion
+	Workers int           // Amount of workers, GOMAXPROCS workers are used if it is smaller than 1
+	Config  TreeConfig    // Configuration of the tree
+	Options []ForceOption // Options used to calculate the forces
+}
+
+// Accelerations builds a tree out of the stars and calculates their accelerations
+func (p TreeAccelerations) Accelerations(stars []Star2D, t float64) ([]Vec2, error) {
+	buildOpts := []BuildOption{WithTreeConfig(p.Config)}
+	if p.Workers > 0 {
+		buildOpts = append(buildOpts, WithWorkers(p.Workers))
+	}
+
+	root, err := BuildTree(stars, buildOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return root.ComputeAccelerations(stars, p.Theta, p.Workers, p.Options...), nil
+}
+
+// DirectAccelerations calculates the accelerations by summing up the contributions of all the stars using DirectSum
+type DirectAccelerations struct {
+	Workers int           // Amount of workers, GOMAXPROCS workers are used if it is smaller than 1
+	Options []ForceOption // Options used to calculate the forces
+}
+
+// Accelerations calculates the accelerations of the stars using DirectSum
+func (p DirectAccelerations) Accelerations(stars []Star2D, t float64) ([]Vec2, error) {
+	accelerations, _ := DirectSum(stars, p.Workers, p.Options...)
+	return accelerations, nil
+}
+
+// Integrator advances stars in time
+type Integrator interface {
+
+	// Step advances the positions and the velocities of the given stars from the time t to the time t+dt using
+	// the accelerations of the given provider. The stars are modified in place.
+	Step(stars []Star2D, t float64, dt float64, provider AccelerationProvider) error
+}
+
+// Euler is the semi-implicit Euler method used by Star2D.CalcNewPos: the velocity is accelerated first and the star
+// is moved using the new velocity afterwards. It is of first order and calculates the accelerations once per step.
+type Euler struct{}
+
+// Step advances the stars by a single Euler step
+func (Euler) Step(stars []Star2D, t float64, dt float64, provider AccelerationProvider) error {
+	accelerations, err := provider.Accelerations(stars, t)
+	if err != nil {
+		return err
+	}
+
+	for i := range stars {
+		stars[i].Accelerate(accelerations[i], dt)
+	}
+	return nil
+}
+
+// accelerationCache keeps the accelerations calculated at the end of a step, so the next step starting with the
+// same stars at the same time doesn't have to calculate them again
+type accelerationCache struct {
+	stars         []Star2D // stars at the end of the previous step
+	t             float64  // time at the end of the previous step
+	accelerations []Vec2   // accelerations of the stars at the end of the previous step
+}
+
+// get returns the cached accelerations if the stars and the time are the ones at the end of the previous step and
+// calculates them using the provider otherwise. The times only have to match up to rounding errors, as t+dt of the
+// previous step and the t of the next step are often calculated differently.
+func (c *accelerationCache) get(stars []Star2D, t float64, provider AccelerationProvider) ([]Vec2, error) {
+	sameTime := math.Abs(c.t-t) <= 1e-12*math.Max(math.Abs(c.t), math.Abs(t))
+	if c.accelerations != nil && sameTime && len(c.stars) == len(stars) {
+		cached := true
+		for i := range stars {
+			if stars[i] != c.stars[i] {
+				cached = false
+				break
+			}
+		}
+		if cached {
+			return c.accelerations, nil
+		}
+	}
+	return provider.Accelerations(stars, t)
+}
+
+// store remembers the accelerations of the stars at the end of a step
+func (c *accelerationCache) store(stars []Star2D, t float64, accelerations []Vec2) {
+	c.stars = append(c.stars[:0], stars...)
+	c.t = t
+	c.accelerations = accelerations
+}
+
+// Leapfrog is the kick-drift-kick leapfrog: the velocity is accelerated for half a step, the star is moved for a
+// whole step and the velocity is accelerated for another half step using the acceleration at the new position.
+// It is symplectic and of second order. The acceleration at the end of a step is kept and reused by the next step
+// if it starts with the same stars at the same time, so the accelerations are calculated once per step when
+// stepping continuously. The zero value is ready to use, the cache assumes the same provider is used for every step.
+type Leapfrog struct {
+	cache accelerationCache
+}
+
+// Step advances the stars by a single kick-drift-kick step
+func (l *Leapfrog) Step(stars []Star2D, t float64, dt float64, provider AccelerationProvider) error {
+	accelerations, err := l.cache.get(stars, t, provider)
+	if err != nil {
+		return err
+	}
+
+	for i := range stars {
+		stars[i].AccelerateVelocity(accelerations[i], dt/2)
+		stars[i].Move(dt)
+	}
+
+	accelerations, err = provider.Accelerations(stars, t+dt)
+	if err != nil {
+		return err
+	}
+
+	for i := range stars {
+		stars[i].AccelerateVelocity(accelerations[i], dt/2)
+	}
+	l.cache.store(stars, t+dt, accelerations)
+	return nil
+}
+
+// VelocityVerlet moves the stars using their velocity and acceleration first and accelerates their velocity using
+// the mean of the acceleration at the old and at the new position afterwards. It is symplectic and of second order.
+// Like the Leapfrog, it reuses the acceleration at the end of a step in the next step, so the accelerations are
+// calculated once per step when stepping continuously. The zero value is ready to use, the cache assumes the same
+// provider is used for every step.
+type VelocityVerlet struct {
+	cache accelerationCache
+}
+
+// Step advances the stars by a single velocity Verlet step
+func (v *VelocityVerlet) Step(stars []Star2D, t float64, dt float64, provider AccelerationProvider) error {
+	accelerations, err := v.cache.get(stars, t, provider)
+	if err != nil {
+		return err
+	}
+
+	for i := range stars {
+		stars[i].C.X += stars[i].V.X*dt + accelerations[i].X*dt*dt/2
+		stars[i].C.Y += stars[i].V.Y*dt + accelerations[i].Y*dt*dt/2
+	}
+
+	newAccelerations, err := provider.Accelerations(stars, t+dt)
+	if err != nil {
+		return err
+	}
+
+	for i := range stars {
+		stars[i].AccelerateVelocity(accelerations[i].Add(newAccelerations[i]), dt/2)
+	}
+	v.cache.store(stars, t+dt, newAccelerations)
+	return nil
+}
+
+// Yoshida4 is the fourth order symplectic integrator of Yoshida (1990) composed out of three leapfrog steps, the
+// second one going backwards in time. It calculates the accelerations three times per step.
+type Yoshida4 struct{}
+
+// coefficients of the drifts (c) and the kicks (d) of the Yoshida4 integrator
+var (
+	yoshidaW1 = 1 / (2 - math.Cbrt(2))
+	yoshidaW0 = -math.Cbrt(2) / (2 - math.Cbrt(2))
+	yoshidaC  = [4]float64{yoshidaW1 / 2, (yoshidaW0 + yoshidaW1) / 2, (yoshidaW0 + yoshidaW1) / 2, yoshidaW1 / 2}
+	yoshidaD  = [3]float64{yoshidaW1, yoshidaW0, yoshidaW1}
+)
+
+// Step advances the stars by a single Yoshida4 step
+func (Yoshida4) Step(stars []Star2D, t float64, dt float64, provider AccelerationProvider) error {
+	time := t
+	for i, c := range yoshidaC {
+		for j := range stars {
+			stars[j].Move(c * dt)
+		}
+		time += c * dt
+
+		if i == len(yoshidaD) {
+			break
+		}
+
+		accelerations, err := provider.Accelerations(stars, time)
+		if err != nil {
+			return err
+		}
+		for j := range stars {
+			stars[j].AccelerateVelocity(accelerations[j], yoshidaD[i]*dt)
+		}
+	}
+	return nil
+}
+
+// RK4 is the classical fourth order Runge-Kutta method. It is not symplectic, so the energy drifts over long runs,
+// and calculates the accelerations four times per step.
+type RK4 struct{}
+
+// Step advances the stars by a single RK4 step
+func (RK4) Step(stars []Star2D, t float64, dt float64, provider AccelerationProvider) error {
+	start := append([]Star2D(nil), stars...)
+	stage := make([]Star2D, len(stars))
+
+	// the derivatives of the positions (velocities) and of the velocities (accelerations) of the four stages
+	var velocities [4][]Vec2
+	var accelerations [4][]Vec2
+
+	stageOffsets := [4]float64{0, dt / 2, dt / 2, dt}
+	for k := range stageOffsets {
+		velocities[k] = make([]Vec2, len(stars))
+
+		// calculate the state of the stage using the derivatives of the previous stage
+		for i, star := range start {
+			stage[i] = star
+			if k > 0 {
+				stage[i].C = star.C.Add(velocities[k-1][i].Multiply(stageOffsets[k]))
+				stage[i].V = star.V.Add(accelerations[k-1][i].Multiply(stageOffsets[k]))
+			}
+			velocities[k][i] = stage[i].V
+		}
+
+		var err error
+		accelerations[k], err = provider.Accelerations(stage, t+stageOffsets[k])
+		if err != nil {
+			return err
+		}
+	}
+
+	// combine the derivatives of the stages using the weights 1, 2, 2, 1
+	for i := range stars {
+		var velocity Vec2 = Vec2{
+			X: velocities[0][i].X + 2*velocities[1][i].X + 2*velocities[2][i].X + velocities[3][i].X,
+			Y: velocities[0][i].Y + 2*velocities[1][i].Y + 2*velocities[2][i].Y + velocities[3][i].Y,
+		}
+		var acceleration Vec2 = Vec2{
+			X: accelerations[0][i].X + 2*accelerations[1][i].X + 2*accelerations[2][i].X + accelerations[3][i].X,
+			Y: accelerations[0][i].Y + 2*accelerations[1][i].Y + 2*accelerations[2][i].Y + accelerations[3][i].Y,
+		}
+
+		stars[i].C = start[i].C.Add(velocity.Multiply(dt / 6))
+		stars[i].V = start[i].V.Add(acceleration.Multiply(dt / 6))
+	}
+	return nil
+}
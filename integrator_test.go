@@ -0,0 +1,209 @@
+// integrator_test.go provides tests for integrator.go
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// harmonicOscillator accelerates every star towards the origin proportional to its distance, every star moves on
+// an ellipse with a period of 2*pi
+var harmonicOscillator = AccelerationFunc(func(stars []Star2D, t float64) ([]Vec2, error) {
+	accelerations := make([]Vec2, len(stars))
+	for i, star := range stars {
+		accelerations[i] = Vec2{-star.C.X, -star.C.Y}
+	}
+	return accelerations, nil
+})
+
+// An integrator advances the stars using the accelerations calculated by a provider
+func ExampleLeapfrog() {
+	stars := []Star2D{
+		NewStar2D(Vec2{-0.5, 0}, Vec2{0, -0.5}, 0.5),
+		NewStar2D(Vec2{0.5, 0}, Vec2{0, 0.5}, 0.5),
+	}
+	provider := DirectAccelerations{Workers: 1, Options: []ForceOption{WithUnits(NBodyUnits)}}
+
+	// the stars orbit each other on a circle with a period of 2*pi
+	var integrator Integrator = &Leapfrog{}
+	t, dt := 0.0, 2*math.Pi/1000
+	for i := 0; i < 1000; i++ {
+		if err := integrator.Step(stars, t, dt, provider); err != nil {
+			fmt.Println(err)
+			return
+		}
+		t += dt
+	}
+
+	fmt.Printf("%.3f %.3f\n", stars[0].C.X, stars[0].V.Y)
+	fmt.Printf("%.3f %.3f\n", stars[1].C.X, stars[1].V.Y)
+	// Output:
+	// -0.500 -0.500
+	// 0.500 0.500
+}
+
+func TestIntegrator_order(t *testing.T) {
+	tests := []struct {
+		name       string
+		integrator Integrator
+		order      float64
+	}{
+		{
+			name:       "Euler",
+			integrator: Euler{},
+			order:      1,
+		},
+		{
+			name:       "Leapfrog",
+			integrator: &Leapfrog{},
+			order:      2,
+		},
+		{
+			name:       "VelocityVerlet",
+			integrator: &VelocityVerlet{},
+			order:      2,
+		},
+		{
+			name:       "Yoshida4",
+			integrator: Yoshida4{},
+			order:      4,
+		},
+		{
+			name:       "RK4",
+			integrator: RK4{},
+			order:      4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			// integrate a single period of the harmonic oscillator, the stars have to end up where they started
+			positionError := func(steps int) float64 {
+				stars := []Star2D{NewStar2D(Vec2{1, 0}, Vec2{0, 0.5}, 1)}
+				dt := 2 * math.Pi / float64(steps)
+				for i := 0; i < steps; i++ {
+					if err := tt.integrator.Step(stars, float64(i)*dt, dt, harmonicOscillator); err != nil {
+						t.Fatalf("%v.Step() error = %v", tt.name, err)
+					}
+				}
+				return math.Hypot(stars[0].C.X-1, stars[0].C.Y) + math.Hypot(stars[0].V.X, stars[0].V.Y-0.5)
+			}
+
+			// halving the timestep reduces the error by 2^order
+			coarse, fine := positionError(100), positionError(200)
+			if got := math.Log2(coarse / fine); got < tt.order-0.2 {
+				t.Errorf("%v.Step() order = %v (errors %v %v), want %v", tt.name, got, coarse, fine, tt.order)
+			}
+		})
+	}
+}
+
+func TestIntegrator_providers(t *testing.T) {
+	stars := randomStars(200, 10, 23)
+	for i := range stars {
+		stars[i].V = Vec2{}
+		stars[i].M /= 100
+	}
+	opts := []ForceOption{WithUnits(NBodyUnits), WithSoftening(PlummerSoftening, 0.5)}
+
+	direct := append([]Star2D(nil), stars...)
+	tree := append([]Star2D(nil), stars...)
+
+	directIntegrator, treeIntegrator := &Leapfrog{}, &Leapfrog{}
+	for i := 0; i < 20; i++ {
+		if err := directIntegrator.Step(direct, float64(i)*0.01, 0.01, DirectAccelerations{Options: opts}); err != nil {
+			t.Fatalf("Leapfrog.Step() error = %v", err)
+		}
+		if err := treeIntegrator.Step(tree, float64(i)*0.01, 0.01, TreeAccelerations{Theta: 0.3, Options: opts}); err != nil {
+			t.Fatalf("Leapfrog.Step() error = %v", err)
+		}
+	}
+
+	// the tree approximates the direct sum
+	for i := range stars {
+		if math.Hypot(direct[i].C.X-tree[i].C.X, direct[i].C.Y-tree[i].C.Y) > 1e-4 {
+			t.Errorf("Leapfrog.Step() using the tree = %v, using the direct sum %v", tree[i].C, direct[i].C)
+		}
+	}
+
+	// errors of the provider are returned
+	duplicates := []Star2D{NewStar2D(Vec2{1, 1}, Vec2{0, 0}, 1), NewStar2D(Vec2{1, 1}, Vec2{0, 0}, 1)}
+	if err := (&Leapfrog{}).Step(duplicates, 0, 0.01, TreeAccelerations{}); errors.Is(err, ErrDuplicatePosition) == false {
+		t.Errorf("Leapfrog.Step() error = %v, want %v", err, ErrDuplicatePosition)
+	}
+}
+
+func TestIntegrator_reuseAccelerations(t *testing.T) {
+	tests := []struct {
+		name       string
+		integrator func() Integrator
+	}{
+		{
+			name:       "Leapfrog",
+			integrator: func() Integrator { return &Leapfrog{} },
+		},
+		{
+			name:       "VelocityVerlet",
+			integrator: func() Integrator { return &VelocityVerlet{} },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			provider := AccelerationFunc(func(stars []Star2D, t float64) ([]Vec2, error) {
+				calls++
+				return harmonicOscillator(stars, t)
+			})
+
+			// the acceleration at the end of a step is reused by the next step
+			stars := []Star2D{NewStar2D(Vec2{1, 0}, Vec2{0, 0.5}, 1)}
+			integrator := tt.integrator()
+			for i := 0; i < 10; i++ {
+				if err := integrator.Step(stars, float64(i)*0.1, 0.1, provider); err != nil {
+					t.Fatalf("%v.Step() error = %v", tt.name, err)
+				}
+			}
+			if calls != 11 {
+				t.Errorf("%v.Step() calculated the accelerations %v times in 10 steps, want 11", tt.name, calls)
+			}
+
+			// the result is the same as without reusing the accelerations
+			want := []Star2D{NewStar2D(Vec2{1, 0}, Vec2{0, 0.5}, 1)}
+			for i := 0; i < 10; i++ {
+				if err := tt.integrator().Step(want, float64(i)*0.1, 0.1, provider); err != nil {
+					t.Fatalf("%v.Step() error = %v", tt.name, err)
+				}
+			}
+			if stars[0] != want[0] {
+				t.Errorf("%v.Step() = %v reusing the accelerations, want %v", tt.name, stars[0], want[0])
+			}
+
+			// the accelerations are calculated again if the stars were changed in between two steps
+			calls = 0
+			stars[0].C.X += 1
+			if err := integrator.Step(stars, 1, 0.1, provider); err != nil {
+				t.Fatalf("%v.Step() error = %v", tt.name, err)
+			}
+			if calls != 2 {
+				t.Errorf("%v.Step() calculated the accelerations %v times after changing the stars, want 2", tt.name, calls)
+			}
+		})
+	}
+}
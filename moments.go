@@ -0,0 +1,149 @@
+// moments.go calculates the mass moments of the nodes in a tree
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+import "sync"
+
+// Quadrupole defines the symmetric quadrupole tensor Q_ij = sum m (3 x_i x_j - |x|^2 delta_ij) of a cell in the plane
+// of the galaxy. The positions x are measured relative to the center of mass of the cell.
+type Quadrupole struct {
+	XX float64
+	XY float64
+	YY float64
+}
+
+// Add returns the sum of the quadrupole q and the quadrupole q2
+func (q Quadrupole) Add(q2 Quadrupole) Quadrupole {
+	return Quadrupole{q.XX + q2.XX, q.XY + q2.XY, q.YY + q2.YY}
+}
+
+// apply returns the product of the quadrupole tensor and the vector v
+func (q Quadrupole) apply(v Vec2) Vec2 {
+	return Vec2{q.XX*v.X + q.XY*v.Y, q.XY*v.X + q.YY*v.Y}
+}
+
+// pointQuadrupole returns the quadrupole of a point mass m at the offset d from the center of mass.
+// Adding it to the quadrupole of a cell shifts that quadrupole from the center of mass of the cell to the point
+// d away from it (parallel axis theorem).
+func pointQuadrupole(m float64, d Vec2) Quadrupole {
+	return Quadrupole{
+		XX: m * (2*d.X*d.X - d.Y*d.Y),
+		XY: m * 3 * d.X * d.Y,
+		YY: m * (2*d.Y*d.Y - d.X*d.X),
+	}
+}
+
+// parallelMomentsThreshold is the minimum amount of bodies a subtree must contain for its moments to be calculated
+// in its own goroutine
+const parallelMomentsThreshold = 4096
+
+// ComputeMoments calculates the TotalMass, the CenterOfMass and the Quadrupole of every node in the tree it is called on. The tree
+// is traversed bottom up once, so every node is visited exactly once. Every call starts from scratch, so calling it
+// multiple times results in the same moments. Subtrees that held more than parallelMomentsThreshold bodies the last
+// time their moments were calculated are handled concurrently.
+func (n *BodyNode[B]) ComputeMoments() {
+	var wg sync.WaitGroup
+	for _, subtree := range n.Subtrees {
+		if subtree == nil {
+			continue
+		}
+
+		if subtree.bodyCount >= parallelMomentsThreshold {
+			wg.Add(1)
+			go func(subtree *BodyNode[B]) {
+				defer wg.Done()
+				subtree.ComputeMoments()
+			}(subtree)
+			continue
+		}
+
+		subtree.ComputeMoments()
+	}
+	wg.Wait()
+
+	n.updateMoments()
+}
+
+// CalcCenterOfMass calculates the center of mass for every node in the tree and returns the center of mass of the
+// node it is called on
+func (n *BodyNode[B]) CalcCenterOfMass() Vec2 {
+	n.ComputeMoments()
+	return n.CenterOfMass
+}
+
+// CalcTotalMass calculates the total mass for every node in the tree and returns the total mass of the node it is
+// called on
+func (n *BodyNode[B]) CalcTotalMass() float64 {
+	n.ComputeMoments()
+	return n.TotalMass
+}
+
+// updateMoments recalculates the total mass, the center of mass and the quadrupole of the node using its own bodies
+// and the moments of its direct subtrees. The moments of the subtrees are expected to be up to date.
+// The subtrees are locked one after another while their moments are read.
+func (n *BodyNode[B]) updateMoments() {
+	totalMass := 0.0
+	weightedPosition := Vec2{}
+
+	// the moments of the subtrees are copied, so that they can be shifted to the center of mass of the node
+	// without locking the subtrees a second time
+	var subtrees [4]struct {
+		totalMass    float64
+		centerOfMass Vec2
+		quadrupole   Quadrupole
+	}
+	bodyCount := len(n.Bodies)
+	for _, body := range n.Bodies {
+		totalMass += body.Mass()
+		weightedPosition = weightedPosition.Add(body.Position().Multiply(body.Mass()))
+	}
+	for i, subtree := range n.Subtrees {
+		if subtree != nil {
+			subtree.mutex.Lock()
+			subtrees[i].totalMass = subtree.TotalMass
+			subtrees[i].centerOfMass = subtree.CenterOfMass
+			subtrees[i].quadrupole = subtree.Quadrupole
+			bodyCount += subtree.bodyCount
+			subtree.mutex.Unlock()
+
+			totalMass += subtrees[i].totalMass
+			weightedPosition = weightedPosition.Add(subtrees[i].centerOfMass.Multiply(subtrees[i].totalMass))
+		}
+	}
+
+	n.TotalMass = totalMass
+	n.bodyCount = bodyCount
+
+	// a node without any mass does not have a center of mass
+	if totalMass == 0 {
+		n.CenterOfMass = Vec2{}
+		n.Quadrupole = Quadrupole{}
+		return
+	}
+
+	n.CenterOfMass = weightedPosition.Multiply(1 / totalMass)
+
+	quadrupole := Quadrupole{}
+	for _, body := range n.Bodies {
+		quadrupole = quadrupole.Add(pointQuadrupole(body.Mass(), body.Position().Sub(n.CenterOfMass)))
+	}
+	for _, subtree := range subtrees {
+		offset := subtree.centerOfMass.Sub(n.CenterOfMass)
+		quadrupole = quadrupole.Add(subtree.quadrupole).Add(pointQuadrupole(subtree.totalMass, offset))
+	}
+	n.Quadrupole = quadrupole
+}
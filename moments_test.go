@@ -0,0 +1,207 @@
+// moments_test.go provides tests for moments.go
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// ComputeMoments calculates the total mass, the center of mass and the quadrupole of every node in the tree.
+// Calling it multiple times does not change the result.
+func ExampleNode_ComputeMoments() {
+	root := NewRoot(100)
+
+	// There will be no error handling here, we'll assume that everything goes right..
+	_ = root.Insert(NewStar2D(Vec2{10, 20}, Vec2{0, 0}, 10))
+	_ = root.Insert(NewStar2D(Vec2{-10, -20}, Vec2{0, 0}, 30))
+
+	root.ComputeMoments()
+	root.ComputeMoments()
+
+	fmt.Println(root.TotalMass, root.CenterOfMass, root.Quadrupole)
+	fmt.Println(root.Subtrees[1].TotalMass, root.Subtrees[1].CenterOfMass, root.Subtrees[1].Quadrupole)
+	// Output:
+	// 40 {-5 -10} {-6000 18000 21000}
+	// 10 {10 20} {0 0 0}
+}
+
+func TestNode_ComputeMoments(t *testing.T) {
+	tests := []struct {
+		name   string
+		stars  []Star2D
+		config TreeConfig
+	}{
+		{
+			name:  "Empty tree",
+			stars: []Star2D{},
+		},
+		{
+			name:  "Single star",
+			stars: []Star2D{NewStar2D(Vec2{10, 20}, Vec2{0, 0}, 10)},
+		},
+		{
+			name:  "Many stars computed concurrently",
+			stars: randomStars(50000, 1e4, 8),
+		},
+		{
+			name:   "Buckets",
+			stars:  randomStars(5000, 1e4, 9),
+			config: NewTreeConfig(4, 1),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := BuildTree(tt.stars, WithTreeConfig(tt.config))
+			if err != nil {
+				t.Fatalf("BuildTree() error = %v", err)
+			}
+
+			// break the moments of every node, ComputeMoments has to calculate them from scratch
+			var walk func(node *Node)
+			walk = func(node *Node) {
+				node.TotalMass = -1
+				node.CenterOfMass = Vec2{math.NaN(), math.NaN()}
+				node.Quadrupole = Quadrupole{math.NaN(), math.NaN(), math.NaN()}
+				for _, subtree := range node.Subtrees {
+					if subtree != nil {
+						walk(subtree)
+					}
+				}
+			}
+			walk(n)
+
+			for i := 0; i < 3; i++ {
+				n.ComputeMoments()
+
+				// check the moments of every node against the stars stored below it
+				var check func(node *Node)
+				check = func(node *Node) {
+					totalMass := 0.0
+					weightedPosition := Vec2{}
+					for _, star := range node.GetAllStars() {
+						totalMass += star.M
+						weightedPosition = weightedPosition.Add(star.C.Multiply(star.M))
+					}
+					centerOfMass := Vec2{}
+					if totalMass != 0 {
+						centerOfMass = weightedPosition.Multiply(1 / totalMass)
+					}
+					quadrupole := Quadrupole{}
+					for _, star := range node.GetAllStars() {
+						offset := Vec2{star.C.X - centerOfMass.X, star.C.Y - centerOfMass.Y}
+						quadrupole = quadrupole.Add(pointQuadrupole(star.M, offset))
+					}
+					tolerance := 1e-9 * (math.Abs(quadrupole.XX) + math.Abs(quadrupole.XY) + math.Abs(quadrupole.YY) + 1)
+
+					if math.Abs(node.TotalMass-totalMass) > 1e-9*totalMass ||
+						math.Abs(node.CenterOfMass.X-centerOfMass.X) > 1e-9*n.Boundary.Width ||
+						math.Abs(node.CenterOfMass.Y-centerOfMass.Y) > 1e-9*n.Boundary.Width {
+						t.Fatalf("Node.ComputeMoments() = %v %v, want %v %v",
+							node.TotalMass, node.CenterOfMass, totalMass, centerOfMass)
+					}
+					if math.Abs(node.Quadrupole.XX-quadrupole.XX) > tolerance ||
+						math.Abs(node.Quadrupole.XY-quadrupole.XY) > tolerance ||
+						math.Abs(node.Quadrupole.YY-quadrupole.YY) > tolerance {
+						t.Fatalf("Node.ComputeMoments() quadrupole = %v, want %v", node.Quadrupole, quadrupole)
+					}
+
+					for _, subtree := range node.Subtrees {
+						if subtree != nil {
+							check(subtree)
+						}
+					}
+				}
+				check(n)
+			}
+		})
+	}
+}
+
+func TestNode_ComputeMoments_autoExpandStarCount(t *testing.T) {
+	n := NewRootWithConfig(100, TreeConfig{AutoExpand: true})
+	stars := append(randomStars(100, 40, 10), NewStar2D(Vec2{500, 300}, Vec2{0, 0}, 5))
+	for _, star := range stars {
+		if err := n.Insert(star); err != nil {
+			t.Fatalf("Node.Insert() error = %v", err)
+		}
+	}
+
+	// the amount of stars maintained while growing is the one counted from scratch
+	bodyCount := n.bodyCount
+	n.ComputeMoments()
+	if bodyCount != len(stars) || n.bodyCount != len(stars) {
+		t.Errorf("Node.bodyCount = %v after growing and %v after ComputeMoments, want %v",
+			bodyCount, n.bodyCount, len(stars))
+	}
+}
+
+func TestPointQuadrupole(t *testing.T) {
+	type args struct {
+		m float64
+		d Vec2
+	}
+	tests := []struct {
+		name string
+		args args
+		want Quadrupole
+	}{
+		{
+			name: "star at the center of mass",
+			args: args{m: 10, d: Vec2{0, 0}},
+			want: Quadrupole{0, 0, 0},
+		},
+		{
+			name: "star on the x axis",
+			args: args{m: 10, d: Vec2{10, 0}},
+			want: Quadrupole{2000, 0, -1000},
+		},
+		{
+			name: "star on the diagonal",
+			args: args{m: 2, d: Vec2{-1, -1}},
+			want: Quadrupole{2, 6, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pointQuadrupole(tt.args.m, tt.args.d); got != tt.want {
+				t.Errorf("pointQuadrupole() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNode_Insert_autoExpandQuadrupole(t *testing.T) {
+	n := NewRootWithConfig(100, TreeConfig{AutoExpand: true})
+	stars := append(randomStars(100, 40, 11), NewStar2D(Vec2{500, 300}, Vec2{0, 0}, 5))
+	for _, star := range stars {
+		if err := n.Insert(star); err != nil {
+			t.Fatalf("Node.Insert() error = %v", err)
+		}
+	}
+
+	// the quadrupole maintained while growing is the one calculated from scratch
+	quadrupole := n.Quadrupole
+	n.ComputeMoments()
+	tolerance := 1e-9 * (math.Abs(n.Quadrupole.XX) + math.Abs(n.Quadrupole.XY) + math.Abs(n.Quadrupole.YY))
+	if math.Abs(quadrupole.XX-n.Quadrupole.XX) > tolerance ||
+		math.Abs(quadrupole.XY-n.Quadrupole.XY) > tolerance ||
+		math.Abs(quadrupole.YY-n.Quadrupole.YY) > tolerance {
+		t.Errorf("Node.Quadrupole = %v after growing, want %v", quadrupole, n.Quadrupole)
+	}
+}
@@ -0,0 +1,472 @@
+// octree.go defines the octree storing galaxies in three dimensions
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+import (
+	"fmt"
+	"math"
+)
+
+// Quadrupole3D is the traceless quadrupole moment Q_ij = sum m*(3*d_i*d_j - |d|^2*delta_ij) of a cell around its
+// center of mass. The tensor is symmetric, so only the upper triangle is stored.
+type Quadrupole3D struct {
+	XX float64
+	XY float64
+	XZ float64
+	YY float64
+	YZ float64
+	ZZ float64
+}
+
+// Add returns the sum of the quadrupole q and the quadrupole q2
+func (q Quadrupole3D) Add(q2 Quadrupole3D) Quadrupole3D {
+	return Quadrupole3D{q.XX + q2.XX, q.XY + q2.XY, q.XZ + q2.XZ, q.YY + q2.YY, q.YZ + q2.YZ, q.ZZ + q2.ZZ}
+}
+
+// apply returns the product of the quadrupole tensor and the vector v
+func (q Quadrupole3D) apply(v Vec3) Vec3 {
+	return Vec3{
+		X: q.XX*v.X + q.XY*v.Y + q.XZ*v.Z,
+		Y: q.XY*v.X + q.YY*v.Y + q.YZ*v.Z,
+		Z: q.XZ*v.X + q.YZ*v.Y + q.ZZ*v.Z,
+	}
+}
+
+// pointQuadrupole3D returns the quadrupole of a point mass m at the offset d from the center of mass.
+// Adding it to the quadrupole of a cell shifts that quadrupole from the center of mass of the cell to the point
+// d away from it (parallel axis theorem).
+func pointQuadrupole3D(m float64, d Vec3) Quadrupole3D {
+	r2 := d.Len2()
+	return Quadrupole3D{
+		XX: m * (3*d.X*d.X - r2),
+		XY: m * 3 * d.X * d.Y,
+		XZ: m * 3 * d.X * d.Z,
+		YY: m * (3*d.Y*d.Y - r2),
+		YZ: m * 3 * d.Y * d.Z,
+		ZZ: m * (3*d.Z*d.Z - r2),
+	}
+}
+
+// Node3D defines a node in the octree storing a galaxy in three dimensions. It works like Node, using eight
+// octants instead of four quadrants. The tree does not grow automatically and isn't safe for concurrent use.
+type Node3D struct {
+	Boundary     BoundingBox3D // Spatial outreach of the octree
+	CenterOfMass Vec3          // Center of mass of the cell
+	TotalMass    float64       // Total mass of all the stars in the cell
+	Quadrupole   Quadrupole3D  // Quadrupole moment of the cell around its center of mass
+	Depth        int           // Depth of the cell in the tree
+	Config       TreeConfig    // Configuration of the tree the cell is part of, AutoExpand is not supported
+
+	Star  Star3D   // The actual star
+	Stars []Star3D // The remaining stars of a leaf holding more than a single star
+
+	// NW, NE, SW, SE above the center, NW, NE, SW, SE below the center
+	Subtrees [8]*Node3D // The child subtrees
+}
+
+// NewRoot3D returns a pointer to a node defined as a root node of an octree. It takes the width of the
+// BoundingBox3D centered at the origin as an argument.
+func NewRoot3D(BoundingBoxWidth float64) *Node3D {
+	return &Node3D{
+		Boundary: BoundingBox3D{
+			Center: Vec3{0, 0, 0},
+			Width:  BoundingBoxWidth,
+		},
+	}
+}
+
+// NewRoot3DWithConfig returns a pointer to a root node using the given width of the BoundingBox3D. The tree grown
+// from that node uses the given configuration.
+func NewRoot3DWithConfig(BoundingBoxWidth float64, config TreeConfig) *Node3D {
+	root := NewRoot3D(BoundingBoxWidth)
+	root.Config = config
+	return root
+}
+
+// NewRoot3DFitting returns a pointer to a root node whose BoundingBox3D tightly fits all of the given stars.
+// The tree grown from that node uses the given configuration.
+func NewRoot3DFitting(stars []Star3D, config TreeConfig) *Node3D {
+	root := NewRoot3DWithConfig(0, config)
+	root.Boundary = NewBoundingBox3DFitting(stars)
+	return root
+}
+
+// NewNode3D creates a new node using the given bounding box
+func NewNode3D(boundary BoundingBox3D) *Node3D {
+	return &Node3D{Boundary: boundary}
+}
+
+// Subdivide the tree
+func (n *Node3D) Subdivide() {
+	for octant := range n.Subtrees {
+		n.Subtrees[octant] = NewNode3D(n.Boundary.Octant(octant))
+		n.Subtrees[octant].Depth = n.Depth + 1
+		n.Subtrees[octant].Config = n.Config
+	}
+}
+
+// leafStars returns all the stars stored directly in the node
+func (n *Node3D) leafStars() []Star3D {
+	if n.Star == (Star3D{}) {
+		return append([]Star3D(nil), n.Stars...)
+	}
+	return append([]Star3D{n.Star}, n.Stars...)
+}
+
+// setLeafStars stores the given stars directly in the node. The first star is stored in the Star field, the
+// remaining ones in the bucket.
+func (n *Node3D) setLeafStars(stars []Star3D) {
+	n.Star = Star3D{}
+	n.Stars = nil
+
+	if len(stars) > 0 {
+		n.Star = stars[0]
+	}
+	if len(stars) > 1 {
+		n.Stars = append([]Star3D{}, stars[1:]...)
+	}
+}
+
+// Insert inserts the given star into the Node3D or the tree it is called on. The moments of all the nodes the
+// star passes on its way down are updated.
+// If the star can't be inserted, an error wrapping one of ErrZeroStar, ErrOutOfBounds, ErrDuplicatePosition or
+// ErrMaxDepthExceeded is returned and the tree is left unchanged.
+func (n *Node3D) Insert(star Star3D) error {
+
+	// the empty star marks empty slots in the tree, so it can't be inserted
+	if star == (Star3D{}) {
+		return fmt.Errorf("could not insert star (%f, %f, %f): %w", star.C.X, star.C.Y, star.C.Z, ErrZeroStar)
+	}
+	if n.Boundary.Contains(star.C) == false {
+		return fmt.Errorf("could not insert star (%f, %f, %f): %w", star.C.X, star.C.Y, star.C.Z, ErrOutOfBounds)
+	}
+
+	err := n.insert(star)
+	if err != nil {
+		return fmt.Errorf("could not insert star (%f, %f, %f): %w", star.C.X, star.C.Y, star.C.Z, err)
+	}
+
+	return nil
+}
+
+// insert recursively inserts the star into the node it is called on
+func (n *Node3D) insert(star Star3D) error {
+
+	// if a subtree is present, insert the star into that subtree
+	if n.Subtrees != ([8]*Node3D{}) && n.Star == (Star3D{}) {
+		err := n.Subtrees[star.getRelativePositionInt(n.Boundary)].insert(star)
+		if err != nil {
+			return err
+		}
+
+		n.updateMoments()
+		return nil
+	}
+
+	// two stars at the same position can't be separated, no matter how often the node is subdivided
+	stars := n.leafStars()
+	for _, leafStar := range stars {
+		if leafStar.C == star.C {
+			return ErrDuplicatePosition
+		}
+	}
+
+	if n.Subtrees == ([8]*Node3D{}) {
+
+		// directly insert the star into the node if there is some space left
+		if len(stars) < n.Config.capacity() {
+			n.setLeafStars(append(stars, star))
+			n.updateMoments()
+			return nil
+		}
+
+		// leaves at the maximum depth can't be subdivided, so the star is stored in the bucket
+		if n.Depth >= n.Config.maxDepth() {
+			if n.Config.buckets() == false {
+				return ErrMaxDepthExceeded
+			}
+			n.setLeafStars(append(stars, star))
+			n.updateMoments()
+			return nil
+		}
+
+		n.Subdivide()
+	}
+
+	// Move the stars blocking the slot into their subtrees
+	n.setLeafStars(nil)
+	for _, blockingStar := range stars {
+		err := n.Subtrees[blockingStar.getRelativePositionInt(n.Boundary)].insert(blockingStar)
+		if err != nil {
+			n.collapse()
+			return err
+		}
+	}
+
+	// Insert the new star into it's subtree
+	err := n.Subtrees[star.getRelativePositionInt(n.Boundary)].insert(star)
+	if err != nil {
+		// undo the subdivision, so the tree is left unchanged
+		n.collapse()
+		return err
+	}
+
+	// the subtrees changed, so the moments of the node have to be updated
+	n.updateMoments()
+
+	return nil
+}
+
+// collapse merges the subtrees of the node back into the node if all of them are leaves and together hold no more
+// stars than a single leaf can hold
+func (n *Node3D) collapse() {
+	stars := n.leafStars()
+
+	for _, subtree := range n.Subtrees {
+		if subtree == nil {
+			continue
+		}
+
+		// subtrees containing subtrees on their own can't be merged
+		if subtree.Subtrees != ([8]*Node3D{}) {
+			return
+		}
+
+		stars = append(stars, subtree.leafStars()...)
+	}
+
+	if len(stars) > n.Config.capacity() {
+		return
+	}
+
+	n.setLeafStars(stars)
+	n.Subtrees = [8]*Node3D{}
+}
+
+// GenForestTree draws the subtree it is called on. If there is a star inside of the root node, the node is drawn
+// The method returns a string depicting the tree in latex forest structure
+func (n *Node3D) GenForestTree(node *Node3D) string {
+
+	returnstring := "["
+
+	// if there are stars in the node, add the stars coordinates to the return string
+	for i, star := range n.leafStars() {
+		if i > 0 {
+			returnstring += "; "
+		}
+		returnstring += fmt.Sprintf("%.0f %.0f %.0f", star.C.X, star.C.Y, star.C.Z)
+	}
+
+	// iterate over all the subtrees and call the GenForestTree method on the subtrees containing children
+	for _, subtree := range n.Subtrees {
+		if subtree != nil {
+			returnstring += subtree.GenForestTree(subtree)
+		} else {
+			returnstring += "[]"
+		}
+	}
+
+	// Post-tree brace
+	returnstring += "]"
+
+	return returnstring
+}
+
+// GetAllStars returns all the stars in the tree it is called on in an array
+func (n *Node3D) GetAllStars() []Star3D {
+	stars := n.leafStars()
+	for _, subtree := range n.Subtrees {
+		if subtree != nil {
+			stars = append(stars, subtree.GetAllStars()...)
+		}
+	}
+	return stars
+}
+
+// ComputeMoments calculates the TotalMass, the CenterOfMass and the Quadrupole of every node in the tree it is
+// called on. The tree is traversed bottom up once, so every node is visited exactly once.
+func (n *Node3D) ComputeMoments() {
+	for _, subtree := range n.Subtrees {
+		if subtree != nil {
+			subtree.ComputeMoments()
+		}
+	}
+	n.updateMoments()
+}
+
+// CalcCenterOfMass calculates the center of mass for every node in the tree and returns the center of mass of the
+// node it is called on
+func (n *Node3D) CalcCenterOfMass() Vec3 {
+	n.ComputeMoments()
+	return n.CenterOfMass
+}
+
+// CalcTotalMass calculates the total mass for every node in the tree and returns the total mass of the node it is
+// called on
+func (n *Node3D) CalcTotalMass() float64 {
+	n.ComputeMoments()
+	return n.TotalMass
+}
+
+// updateMoments recalculates the total mass, the center of mass and the quadrupole of the node using its own stars
+// and the moments of its direct subtrees. The moments of the subtrees are expected to be up to date.
+func (n *Node3D) updateMoments() {
+	totalMass := 0.0
+	weightedPosition := Vec3{}
+
+	stars := n.leafStars()
+	for _, star := range stars {
+		totalMass += star.M
+		weightedPosition = weightedPosition.Add(star.C.Multiply(star.M))
+	}
+	for _, subtree := range n.Subtrees {
+		if subtree != nil {
+			totalMass += subtree.TotalMass
+			weightedPosition = weightedPosition.Add(subtree.CenterOfMass.Multiply(subtree.TotalMass))
+		}
+	}
+
+	n.TotalMass = totalMass
+
+	// a node without any mass does not have a center of mass
+	if totalMass == 0 {
+		n.CenterOfMass = Vec3{}
+		n.Quadrupole = Quadrupole3D{}
+		return
+	}
+
+	n.CenterOfMass = weightedPosition.Multiply(1 / totalMass)
+
+	quadrupole := Quadrupole3D{}
+	for _, star := range stars {
+		quadrupole = quadrupole.Add(pointQuadrupole3D(star.M, star.C.Sub(n.CenterOfMass)))
+	}
+	for _, subtree := range n.Subtrees {
+		if subtree != nil {
+			offset := subtree.CenterOfMass.Sub(n.CenterOfMass)
+			quadrupole = quadrupole.Add(subtree.Quadrupole).Add(pointQuadrupole3D(subtree.TotalMass, offset))
+		}
+	}
+	n.Quadrupole = quadrupole
+}
+
+// CalcAllForces calculates the force acting in between the given star and all the other stars using the given theta.
+// It gets all the other stars from the root node it is called on. The options work like they do for Node, the
+// adaptive softening and the previous acceleration used by the RelativeErrorCriterion are given using
+// WithAdaptiveSoftening3D and WithPreviousAcceleration3D.
+func (n *Node3D) CalcAllForces(star Star3D, theta float64, opts ...ForceOption) Vec3 {
+	options := newForceOptions(opts)
+	return n.calcAllForces(star, theta, &options)
+}
+
+// calcAllForces calculates the force acting on the given star recursively using the given options
+func (n *Node3D) calcAllForces(star Star3D, theta float64, options *forceOptions) Vec3 {
+	var localForce Vec3 = Vec3{}
+
+	// leaves exert the forces of their stars, a star at the same position is the star itself
+	if n.Subtrees == ([8]*Node3D{}) {
+		for _, leafStar := range n.leafStars() {
+			if leafStar.C != star.C {
+				localForce = localForce.Add(calcForce3D(star, leafStar, options, options.softening3D(star, leafStar)))
+			}
+		}
+		return localForce
+	}
+
+	// the node has to be opened -> recurse deeper
+	if n.accept(star, theta, options) == false {
+		for _, subtree := range n.Subtrees {
+			localForce = localForce.Add(subtree.calcAllForces(star, theta, options))
+		}
+		return localForce
+	}
+
+	// the node is approximated by its total mass located at its center of mass
+	nodeStar := Star3D{C: n.CenterOfMass, M: n.TotalMass}
+	localForce = calcForce3D(star, nodeStar, options, options.softening3D(star))
+
+	// correct the force using the quadrupole moment of the node
+	if options.quadrupole {
+		localForce = localForce.Add(n.calcQuadrupoleForce(star, options))
+	}
+
+	return localForce
+}
+
+// accept returns true if the node can be approximated by its moments when calculating the force acting on the star
+func (n *Node3D) accept(star Star3D, theta float64, options *forceOptions) bool {
+	boundary := n.Boundary
+	cell := cellGeometry{
+		width:    boundary.Width,
+		distance: star.C.Distance(n.CenterOfMass),
+		contains: boundary.Contains(star.C),
+		adjacent: math.Abs(star.C.X-boundary.Center.X) < 0.6*boundary.Width &&
+			math.Abs(star.C.Y-boundary.Center.Y) < 0.6*boundary.Width &&
+			math.Abs(star.C.Z-boundary.Center.Z) < 0.6*boundary.Width,
+	}
+
+	// bmax is only needed by the BmaxCriterion
+	if options.criterion == BmaxCriterion {
+		offset := n.CenterOfMass.Sub(boundary.Center)
+		halfWidth := boundary.Width / 2
+		cell.bmax = Vec3{halfWidth + math.Abs(offset.X), halfWidth + math.Abs(offset.Y), halfWidth + math.Abs(offset.Z)}.Len()
+	}
+
+	return acceptGeometry(cell, n.TotalMass, theta, options.previousAcceleration3D.Len(), options)
+}
+
+// calcQuadrupoleForce calculates the force exerted on the star by the quadrupole moment of the node. It is the
+// correction that has to be added to the force exerted by the total mass of the node located at its center of mass.
+func (n *Node3D) calcQuadrupoleForce(star Star3D, options *forceOptions) Vec3 {
+	G := options.units.G()
+
+	// define a vector pointing from the center of mass of the node to the star
+	var r Vec3 = star.C.Sub(n.CenterOfMass)
+	var distanceSquared float64 = r.Len2()
+	if distanceSquared == 0 {
+		return Vec3{}
+	}
+
+	// the acceleration is the negative gradient of the potential -G/2 * (r Q r) / |r|^5
+	var qr Vec3 = n.Quadrupole.apply(r)
+	var rqr float64 = r.Dot(qr)
+	var distance5 float64 = distanceSquared * distanceSquared * math.Sqrt(distanceSquared)
+
+	var acceleration Vec3 = qr.Multiply(G / distance5).Sub(r.Multiply(G * 2.5 * rqr / (distance5 * distanceSquared)))
+
+	// return the force exerted on the star by the quadrupole
+	return acceleration.Multiply(star.M)
+}
+
+// CalcForce3D calculates the force exerted on s1 by s2 and returns a vector representing that force. The options
+// define how the force is softened and which unit system is used.
+func CalcForce3D(s1 Star3D, s2 Star3D, opts ...ForceOption) Vec3 {
+	options := newForceOptions(opts)
+	return calcForce3D(s1, s2, &options, options.softening3D(s1, s2))
+}
+
+// calcForce3D calculates the force exerted on s1 by s2 using the given options and softening length
+func calcForce3D(s1 Star3D, s2 Star3D, options *forceOptions, length float64) Vec3 {
+	kernel := options.kernel
+	if length <= 0 {
+		kernel = NoSoftening
+	}
+
+	var vector Vec3 = s2.C.Sub(s1.C)
+	var factor float64 = softenedForceFactor(kernel, length, vector.Len2())
+	return vector.Multiply(options.units.G() * s1.M * s2.M * factor)
+}
@@ -0,0 +1,546 @@
+// octree_test.go provides tests for octree.go
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// randomStars3D returns n stars at random positions inside of a cube of the given width centered at (0, 0, 0)
+func randomStars3D(n int, width float64, seed int64) []Star3D {
+	random := rand.New(rand.NewSource(seed))
+
+	stars := make([]Star3D, n)
+	for i := range stars {
+		stars[i] = NewStar3D(
+			Vec3{(random.Float64() - 0.5) * width, (random.Float64() - 0.5) * width, (random.Float64() - 0.5) * width},
+			Vec3{random.Float64(), random.Float64(), random.Float64()},
+			random.Float64()*10+1,
+		)
+	}
+	return stars
+}
+
+// The octree is grown by inserting stars the same way the quadtree is
+func ExampleNode3D_GenForestTree() {
+	root := NewRoot3D(100)
+	_ = root.Insert(NewStar3D(Vec3{10, 20, 30}, Vec3{0, 0, 0}, 10))
+	_ = root.Insert(NewStar3D(Vec3{-10, -20, -30}, Vec3{0, 0, 0}, 10))
+
+	fmt.Println(root.GenForestTree(root))
+	// Output:
+	// [[[][][][][][][][]][10 20 30[][][][][][][][]][[][][][][][][][]][[][][][][][][][]][[][][][][][][][]][[][][][][][][][]][-10 -20 -30[][][][][][][][]][[][][][][][][][]]]
+}
+
+func TestNode3D_Subdivide(t *testing.T) {
+	root := NewRoot3DWithConfig(8, NewTreeConfig(5, 0))
+	root.Subdivide()
+
+	want := []Vec3{
+		{-2, 2, 2}, {2, 2, 2}, {-2, -2, 2}, {2, -2, 2},
+		{-2, 2, -2}, {2, 2, -2}, {-2, -2, -2}, {2, -2, -2},
+	}
+	for octant, subtree := range root.Subtrees {
+		if subtree.Boundary != NewBoundingBox3D(want[octant], 4) || subtree.Depth != 1 || subtree.Config != root.Config {
+			t.Errorf("Node3D.Subdivide() octant %v = %v, want a cube of width 4 at %v", octant, subtree.Boundary, want[octant])
+		}
+
+		// a star at the center of the octant is classified into the octant
+		if got := NewStar3D(want[octant], Vec3{}, 1).getRelativePositionInt(root.Boundary); got != octant {
+			t.Errorf("Star3D.getRelativePositionInt() = %v, want %v", got, octant)
+		}
+	}
+}
+
+func TestNode3D_Insert(t *testing.T) {
+	stars := randomStars3D(500, 100, 37)
+
+	tests := []struct {
+		name   string
+		config TreeConfig
+	}{
+		{name: "single star per leaf"},
+		{name: "buckets", config: NewTreeConfig(3, 4)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := NewRoot3DWithConfig(100, tt.config)
+			for _, star := range stars {
+				if err := root.Insert(star); err != nil {
+					t.Fatalf("Node3D.Insert() error = %v", err)
+				}
+			}
+
+			// all the stars can be found in the tree
+			got := root.GetAllStars()
+			want := append([]Star3D(nil), stars...)
+			less := func(stars []Star3D) func(i, j int) bool {
+				return func(i, j int) bool { return stars[i].C.X < stars[j].C.X }
+			}
+			sort.Slice(got, less(got))
+			sort.Slice(want, less(want))
+			if reflect.DeepEqual(got, want) == false {
+				t.Errorf("Node3D.GetAllStars() returned %v stars, want %v", len(got), len(want))
+			}
+
+			// every star is stored inside of the boundary of its leaf
+			var check func(n *Node3D)
+			check = func(n *Node3D) {
+				if n.Depth > tt.config.maxDepth() {
+					t.Errorf("Node3D.Insert() depth = %v, want at most %v", n.Depth, tt.config.maxDepth())
+				}
+				for _, star := range n.leafStars() {
+					if n.Boundary.Contains(star.C) == false {
+						t.Errorf("Node3D.Insert() star %v outside of %v", star.C, n.Boundary)
+					}
+				}
+				for _, subtree := range n.Subtrees {
+					if subtree != nil {
+						check(subtree)
+					}
+				}
+			}
+			check(root)
+		})
+	}
+}
+
+func TestNode3D_Insert_error(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  TreeConfig
+		star    Star3D
+		wantErr error
+	}{
+		{
+			name:    "empty star",
+			star:    Star3D{},
+			wantErr: ErrZeroStar,
+		},
+		{
+			name:    "outside of the boundary",
+			star:    NewStar3D(Vec3{10, 0, 60}, Vec3{}, 1),
+			wantErr: ErrOutOfBounds,
+		},
+		{
+			name:    "duplicate position",
+			star:    NewStar3D(Vec3{10, 20, 30}, Vec3{}, 2),
+			wantErr: ErrDuplicatePosition,
+		},
+		{
+			name:    "maximum depth exceeded",
+			config:  NewTreeConfig(2, 0),
+			star:    NewStar3D(Vec3{11, 21, 31}, Vec3{}, 1),
+			wantErr: ErrMaxDepthExceeded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := NewRoot3DWithConfig(100, tt.config)
+			_ = root.Insert(NewStar3D(Vec3{10, 20, 30}, Vec3{}, 1))
+			_ = root.Insert(NewStar3D(Vec3{-10, -20, -30}, Vec3{}, 1))
+			before := root.GenForestTree(root)
+
+			if err := root.Insert(tt.star); errors.Is(err, tt.wantErr) == false {
+				t.Errorf("Node3D.Insert() error = %v, want %v", err, tt.wantErr)
+			}
+
+			// the tree is left unchanged
+			if after := root.GenForestTree(root); after != before || root.TotalMass != 2 {
+				t.Errorf("Node3D.Insert() changed the tree to %v, want %v", after, before)
+			}
+		})
+	}
+}
+
+func TestNewRoot3DFitting(t *testing.T) {
+	tests := []struct {
+		name  string
+		stars []Star3D
+		want  BoundingBox3D
+	}{
+		{
+			name:  "no stars",
+			stars: []Star3D{},
+			want:  NewBoundingBox3D(Vec3{}, 1),
+		},
+		{
+			name: "stars spanning the z axis",
+			stars: []Star3D{
+				NewStar3D(Vec3{1, 2, -10}, Vec3{}, 1),
+				NewStar3D(Vec3{3, 4, 10}, Vec3{}, 1),
+			},
+			want: NewBoundingBox3D(Vec3{2, 3, 0}, 20),
+		},
+		{
+			name: "stars at positions that aren't finite are left out",
+			stars: []Star3D{
+				NewStar3D(Vec3{1, 2, -10}, Vec3{}, 1),
+				NewStar3D(Vec3{math.NaN(), 0, 0}, Vec3{}, 1),
+				NewStar3D(Vec3{0, math.Inf(-1), 0}, Vec3{}, 1),
+				NewStar3D(Vec3{3, 4, 10}, Vec3{}, 1),
+			},
+			want: NewBoundingBox3D(Vec3{2, 3, 0}, 20),
+		},
+		{
+			name:  "only a star at an infinite position",
+			stars: []Star3D{NewStar3D(Vec3{0, 0, math.Inf(1)}, Vec3{}, 1)},
+			want:  NewBoundingBox3D(Vec3{}, 1),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := NewRoot3DFitting(tt.stars, TreeConfig{})
+			if root.Boundary != tt.want {
+				t.Errorf("NewRoot3DFitting().Boundary = %v, want %v", root.Boundary, tt.want)
+			}
+
+			// the stars that aren't finite can't be inserted
+			for _, star := range tt.stars {
+				err := root.Insert(star)
+				if star.C.IsFinite() == false && errors.Is(err, ErrOutOfBounds) == false {
+					t.Errorf("Node3D.Insert() error = %v, want %v", err, ErrOutOfBounds)
+				}
+			}
+		})
+	}
+}
+
+func TestNode3D_CalcCenterOfMass(t *testing.T) {
+	root := NewRoot3DWithConfig(100, NewTreeConfig(4, 3))
+	stars := randomStars3D(300, 100, 38)
+	for _, star := range stars {
+		if err := root.Insert(star); err != nil {
+			t.Fatalf("Node3D.Insert() error = %v", err)
+		}
+	}
+
+	var totalMass float64
+	var weightedPosition Vec3
+	quadrupole := Quadrupole3D{}
+	for _, star := range stars {
+		totalMass += star.M
+		weightedPosition = weightedPosition.Add(star.C.Multiply(star.M))
+	}
+	centerOfMass := weightedPosition.Multiply(1 / totalMass)
+	for _, star := range stars {
+		quadrupole = quadrupole.Add(pointQuadrupole3D(star.M, star.C.Sub(centerOfMass)))
+	}
+
+	// the moments maintained while inserting equal the ones calculated from scratch
+	for _, recompute := range []bool{false, true} {
+		if recompute {
+			root.ComputeMoments()
+		}
+		if math.Abs(root.TotalMass-totalMass) > 1e-9*totalMass || root.CenterOfMass.Equal(centerOfMass, 1e-9) == false {
+			t.Errorf("Node3D moments = (%v, %v), want (%v, %v)", root.TotalMass, root.CenterOfMass, totalMass, centerOfMass)
+		}
+		got := []float64{root.Quadrupole.XX, root.Quadrupole.XY, root.Quadrupole.XZ, root.Quadrupole.YY, root.Quadrupole.YZ, root.Quadrupole.ZZ}
+		want := []float64{quadrupole.XX, quadrupole.XY, quadrupole.XZ, quadrupole.YY, quadrupole.YZ, quadrupole.ZZ}
+		for i := range got {
+			if math.Abs(got[i]-want[i]) > 1e-9*math.Abs(quadrupole.XX) {
+				t.Errorf("Node3D.Quadrupole = %v, want %v", root.Quadrupole, quadrupole)
+				break
+			}
+		}
+	}
+
+	if got := root.CalcTotalMass(); math.Abs(got-totalMass) > 1e-9*totalMass {
+		t.Errorf("Node3D.CalcTotalMass() = %v, want %v", got, totalMass)
+	}
+	if got := root.CalcCenterOfMass(); got.Equal(centerOfMass, 1e-9) == false {
+		t.Errorf("Node3D.CalcCenterOfMass() = %v, want %v", got, centerOfMass)
+	}
+}
+
+func TestNode3D_CalcAllForces(t *testing.T) {
+	root := NewRoot3D(100)
+	stars := randomStars3D(300, 100, 39)
+	for _, star := range stars {
+		if err := root.Insert(star); err != nil {
+			t.Fatalf("Node3D.Insert() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		theta    float64
+		opts     []ForceOption
+		maxError float64
+	}{
+		{name: "exact", theta: 0, maxError: 1e-12},
+		{name: "classic criterion", theta: 0.5, maxError: 0.02},
+		{name: "bmax criterion", theta: 0.5, opts: []ForceOption{WithOpeningCriterion(BmaxCriterion)}, maxError: 0.01},
+		{name: "quadrupole", theta: 0.5, opts: []ForceOption{WithQuadrupole()}, maxError: 0.005},
+		{name: "softened", theta: 0.5, opts: []ForceOption{WithSoftening(PlummerSoftening, 2)}, maxError: 0.02},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sumSquared := 0.0
+			for _, star := range stars[:50] {
+				want := Vec3{}
+				for _, other := range stars {
+					if other != star {
+						want = want.Add(CalcForce3D(star, other, tt.opts...))
+					}
+				}
+				got := root.CalcAllForces(star, tt.theta, tt.opts...)
+				relativeError := got.Distance(want) / want.Len()
+				sumSquared += relativeError * relativeError
+			}
+
+			if rms := math.Sqrt(sumSquared / 50); rms > tt.maxError {
+				t.Errorf("Node3D.CalcAllForces() rms error = %v, want at most %v", rms, tt.maxError)
+			}
+		})
+	}
+}
+
+func TestNode3D_CalcAllForces_quadrupole(t *testing.T) {
+	root := NewRoot3D(20)
+
+	// the stars form a thick disk, as the quadrupole of a cube of stars is close to zero
+	stars := randomStars3D(200, 20, 40)
+	for i := range stars {
+		stars[i].C.Z *= 0.2
+	}
+	for _, star := range stars {
+		if err := root.Insert(star); err != nil {
+			t.Fatalf("Node3D.Insert() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		star Star3D
+	}{
+		{
+			name: "star on the z axis",
+			star: NewStar3D(Vec3{0, 0, 60}, Vec3{}, 1),
+		},
+		{
+			name: "star on the diagonal",
+			star: NewStar3D(Vec3{-45, -45, 45}, Vec3{}, 1),
+		},
+		{
+			name: "star far away",
+			star: NewStar3D(Vec3{100, 300, -200}, Vec3{}, 1),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := Vec3{}
+			for _, star := range stars {
+				want = want.Add(CalcForce3D(tt.star, star))
+			}
+
+			// the whole tree is accepted as a single cell
+			monopole := root.CalcAllForces(tt.star, 0.5)
+			quadrupole := root.CalcAllForces(tt.star, 0.5, WithQuadrupole())
+
+			forceError := func(force Vec3) float64 {
+				return force.Distance(want) / want.Len()
+			}
+			if forceError(quadrupole) > forceError(monopole)/10 {
+				t.Errorf("Node3D.CalcAllForces() error with quadrupole = %v, without = %v",
+					forceError(quadrupole), forceError(monopole))
+			}
+		})
+	}
+}
+
+func TestNode3D_CalcAllForces_relativeErrorCriterion(t *testing.T) {
+	root := NewRoot3D(100)
+	stars := randomStars3D(300, 100, 41)
+	for _, star := range stars {
+		if err := root.Insert(star); err != nil {
+			t.Fatalf("Node3D.Insert() error = %v", err)
+		}
+	}
+
+	const theta = 0.001
+	sumSquared := 0.0
+	differs := false
+	for _, star := range stars[:50] {
+		want := Vec3{}
+		for _, other := range stars {
+			if other != star {
+				want = want.Add(CalcForce3D(star, other))
+			}
+		}
+
+		got := root.CalcAllForces(star, theta, WithOpeningCriterion(RelativeErrorCriterion),
+			WithPreviousAcceleration3D(want.Multiply(1/star.M)))
+		relativeError := got.Distance(want) / want.Len()
+		sumSquared += relativeError * relativeError
+
+		// without a previous acceleration the classic criterion opens every cell using such a small theta
+		if got != root.CalcAllForces(star, theta, WithOpeningCriterion(RelativeErrorCriterion)) {
+			differs = true
+		}
+	}
+
+	if rms := math.Sqrt(sumSquared / 50); rms > 0.01 {
+		t.Errorf("Node3D.CalcAllForces() rms error = %v, want at most %v", rms, 0.01)
+	}
+	if differs == false {
+		t.Errorf("Node3D.CalcAllForces() ignores the previous acceleration")
+	}
+}
+
+func TestNode3D_CalcAllForces_adaptiveSoftening(t *testing.T) {
+	root := NewRoot3D(100)
+	stars := randomStars3D(100, 100, 42)
+	for _, star := range stars {
+		if err := root.Insert(star); err != nil {
+			t.Fatalf("Node3D.Insert() error = %v", err)
+		}
+	}
+
+	// the softening length of a star is proportional to its mass for this test
+	length := func(star Star3D) float64 {
+		return 5 * star.M
+	}
+	opts := []ForceOption{WithAdaptiveSoftening3D(PlummerSoftening, length)}
+
+	for _, star := range stars[:10] {
+		want := Vec3{}
+		for _, other := range stars {
+			if other != star {
+				want = want.Add(CalcForce3D(star, other, opts...))
+			}
+		}
+
+		got := root.CalcAllForces(star, 0, opts...)
+		if got.Distance(want) > 1e-12*want.Len() {
+			t.Errorf("Node3D.CalcAllForces() = %v, want %v", got, want)
+		}
+	}
+
+	// the softening length of the pair is the larger softening length of both stars
+	s1 := NewStar3D(Vec3{0, 0, 0}, Vec3{}, 0.2)
+	s2 := NewStar3D(Vec3{1, 1, 1}, Vec3{}, 0.6)
+	if got, want := CalcForce3D(s1, s2, opts...), CalcForce3D(s1, s2, WithSoftening(PlummerSoftening, 3)); got != want {
+		t.Errorf("CalcForce3D() = %v, want %v", got, want)
+	}
+
+	// the adaptive softening of a Node doesn't soften the forces in a Node3D
+	got := root.CalcAllForces(stars[0], 0.5, WithAdaptiveSoftening(PlummerSoftening, func(star Star2D) float64 {
+		return star.M
+	}))
+	if want := root.CalcAllForces(stars[0], 0.5); got != want {
+		t.Errorf("Node3D.CalcAllForces() = %v, want %v", got, want)
+	}
+}
+
+func TestCalcForce3D(t *testing.T) {
+	tests := []struct {
+		name string
+		s1   Star3D
+		s2   Star3D
+		opts []ForceOption
+		want Vec3
+	}{
+		{
+			name: "stars on the z axis",
+			s1:   NewStar3D(Vec3{0, 0, 0}, Vec3{}, 2),
+			s2:   NewStar3D(Vec3{0, 0, 2}, Vec3{}, 3),
+			opts: []ForceOption{WithUnits(NBodyUnits)},
+			want: Vec3{0, 0, 1.5},
+		},
+		{
+			name: "stars on the diagonal",
+			s1:   NewStar3D(Vec3{1, 1, 1}, Vec3{}, 1),
+			s2:   NewStar3D(Vec3{0, 0, 0}, Vec3{}, 3),
+			opts: []ForceOption{WithUnits(NBodyUnits)},
+			want: Vec3{-1, -1, -1}.Multiply(1 / math.Sqrt(3)),
+		},
+		{
+			name: "plummer softening",
+			s1:   NewStar3D(Vec3{0, 0, 0}, Vec3{}, 1),
+			s2:   NewStar3D(Vec3{3, 0, 0}, Vec3{}, 1),
+			opts: []ForceOption{WithUnits(NBodyUnits), WithSoftening(PlummerSoftening, 4)},
+			want: Vec3{3.0 / 125, 0, 0},
+		},
+		{
+			name: "SI units",
+			s1:   NewStar3D(Vec3{0, 0, 0}, Vec3{}, 1),
+			s2:   NewStar3D(Vec3{0, -1, 0}, Vec3{}, 1),
+			want: Vec3{0, -GravitationalConstant, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CalcForce3D(tt.s1, tt.s2, tt.opts...)
+			if got.Equal(tt.want, 1e-12*tt.want.Len()) == false {
+				t.Errorf("CalcForce3D() = %v, want %v", got, tt.want)
+			}
+
+			// Newton's third law
+			if back := CalcForce3D(tt.s2, tt.s1, tt.opts...); back.Add(got).Equal(Vec3{}, 1e-12*got.Len()) == false {
+				t.Errorf("CalcForce3D() = %v in the other direction, want %v", back, got.Multiply(-1))
+			}
+		})
+	}
+}
+
+func TestPointQuadrupole3D(t *testing.T) {
+	tests := []struct {
+		name string
+		m    float64
+		d    Vec3
+		want Quadrupole3D
+	}{
+		{
+			name: "star at the center of mass",
+			m:    10,
+			d:    Vec3{0, 0, 0},
+			want: Quadrupole3D{},
+		},
+		{
+			name: "star on the z axis",
+			m:    10,
+			d:    Vec3{0, 0, 10},
+			want: Quadrupole3D{XX: -1000, YY: -1000, ZZ: 2000},
+		},
+		{
+			name: "star on the diagonal",
+			m:    2,
+			d:    Vec3{-1, -1, 1},
+			want: Quadrupole3D{XX: 0, XY: 6, XZ: -6, YY: 0, YZ: -6, ZZ: 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pointQuadrupole3D(tt.m, tt.d)
+			if got != tt.want {
+				t.Errorf("pointQuadrupole3D() = %v, want %v", got, tt.want)
+			}
+
+			// the quadrupole is traceless
+			if trace := got.XX + got.YY + got.ZZ; trace != 0 {
+				t.Errorf("pointQuadrupole3D() trace = %v, want 0", trace)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+// openingCriterion.go defines the criteria deciding which cells are opened while calculating forces
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+import "math"
+
+// OpeningCriterion defines how the tree walk decides whether a cell is approximated by its moments (the cell is
+// accepted) or whether its subtrees have to be visited (the cell is opened). Cells containing the star are always
+// opened, no matter which criterion is used.
+type OpeningCriterion int
+
+const (
+	// ClassicCriterion accepts a cell if s/d < theta, s being the width of the cell and d the distance between the
+	// star and the center of mass of the cell
+	ClassicCriterion OpeningCriterion = iota
+
+	// BmaxCriterion accepts a cell if bmax/d < theta, bmax being the largest distance between the center of mass of
+	// the cell and one of its corners. Cells with a center of mass far away from their geometric center are opened
+	// earlier than using the ClassicCriterion.
+	BmaxCriterion
+
+	// RelativeErrorCriterion accepts a cell if G*M/d^2 * (s/d)^2 < theta * |a|, a being the acceleration of the
+	// star in the previous step given using WithPreviousAcceleration, or WithPreviousAcceleration3D for a Node3D.
+	// theta is the tolerated relative error of the force. Cells containing the star are always opened. Without a
+	// previous acceleration, the ClassicCriterion is used.
+	RelativeErrorCriterion
+)
+
+// WithOpeningCriterion selects the criterion used to decide whether a cell is opened
+func WithOpeningCriterion(criterion OpeningCriterion) ForceOption {
+	return func(options *forceOptions) {
+		options.criterion = criterion
+	}
+}
+
+// WithPreviousAcceleration sets the acceleration of the star in the previous step used by the
+// RelativeErrorCriterion. The acceleration is the force acting on the star divided by its mass. Node3D ignores
+// this option, use WithPreviousAcceleration3D there.
+func WithPreviousAcceleration(acceleration Vec2) ForceOption {
+	return func(options *forceOptions) {
+		options.previousAcceleration = acceleration
+	}
+}
+
+// WithPreviousAcceleration3D sets the acceleration of the star in the previous step used by the
+// RelativeErrorCriterion when calculating the forces in a Node3D. Node ignores this option.
+func WithPreviousAcceleration3D(acceleration Vec3) ForceOption {
+	return func(options *forceOptions) {
+		options.previousAcceleration3D = acceleration
+	}
+}
+
+// WithPreviousAccelerations sets the accelerations of all the stars in the previous step used by the
+// RelativeErrorCriterion when calling ComputeAccelerations, one for every star in the same order as the stars.
+// CalcAllForces ignores this option, use WithPreviousAcceleration there.
+func WithPreviousAccelerations(accelerations []Vec2) ForceOption {
+	return func(options *forceOptions) {
+		options.previousAccelerations = accelerations
+	}
+}
+
+// accept returns true if the node can be approximated by its moments when calculating the force acting on the star
+func (n *BodyNode[B]) accept(star Star2D, theta float64, options *forceOptions) bool {
+	return acceptCell(n.Boundary, n.CenterOfMass, n.TotalMass, star, theta, options)
+}
+
+// cellGeometry describes a cell of a quadtree or an octree as seen from the star the force is calculated for, so the
+// opening criteria can be shared in between both trees
+type cellGeometry struct {
+	width    float64 // width of the cell
+	distance float64 // distance in between the star and the center of mass of the cell
+	bmax     float64 // largest distance in between the center of mass of the cell and one of its corners
+	contains bool    // the star is inside of the cell
+	adjacent bool    // the star is less than 0.6 widths of the cell away from its center in every direction
+}
+
+// acceptCell returns true if the cell defined by its boundary, its center of mass and its total mass can be
+// approximated by its moments when calculating the force acting on the star
+func acceptCell(boundary BoundingBox, centerOfMass Vec2, totalMass float64, star Star2D, theta float64, options *forceOptions) bool {
+	cell := cellGeometry{
+		width:    boundary.Width,
+		distance: star.C.Distance(centerOfMass),
+		contains: boundary.Contains(star.C),
+		adjacent: math.Abs(star.C.X-boundary.Center.X) < 0.6*boundary.Width &&
+			math.Abs(star.C.Y-boundary.Center.Y) < 0.6*boundary.Width,
+	}
+
+	// bmax is only needed by the BmaxCriterion
+	if options.criterion == BmaxCriterion {
+		offset := centerOfMass.Sub(boundary.Center)
+		cell.bmax = Vec2{boundary.Width/2 + math.Abs(offset.X), boundary.Width/2 + math.Abs(offset.Y)}.Len()
+	}
+
+	return acceptGeometry(cell, totalMass, theta, options.previousAcceleration.Len(), options)
+}
+
+// acceptGeometry returns true if the cell described by the given geometry and total mass can be approximated by
+// its moments when calculating the force acting on a star whose acceleration in the previous step has the given
+// magnitude
+func acceptGeometry(cell cellGeometry, totalMass float64, theta float64, previousAcceleration float64, options *forceOptions) bool {
+
+	// a cell containing the star is never accepted, otherwise the star would attract itself through the total mass
+	// of the cell. Using large values of theta, this could happen for the classic criterion.
+	if cell.contains || cell.distance == 0 {
+		return false
+	}
+
+	switch {
+	case options.criterion == BmaxCriterion:
+		return cell.bmax/cell.distance < theta
+
+	case options.criterion == RelativeErrorCriterion && previousAcceleration > 0:
+		G := options.units.G()
+
+		// cells containing the star (or lying right next to it) are always opened
+		if cell.adjacent {
+			return false
+		}
+
+		var sizeRatio float64 = cell.width / cell.distance
+		return G*totalMass/(cell.distance*cell.distance)*sizeRatio*sizeRatio < theta*previousAcceleration
+
+	default:
+		return cell.width/cell.distance < theta
+	}
+}
@@ -0,0 +1,204 @@
+// openingCriterion_test.go provides tests for openingCriterion.go
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// The opening criterion used by CalcAllForces can be selected using an option
+func ExampleWithOpeningCriterion() {
+	root := NewRoot(100)
+
+	// There will be no error handling here, we'll assume that everything goes right..
+	_ = root.Insert(NewStar2D(Vec2{10, 20}, Vec2{0, 0}, 1e10))
+	_ = root.Insert(NewStar2D(Vec2{20, 20}, Vec2{0, 0}, 1e10))
+	_ = root.Insert(NewStar2D(Vec2{-10, -20}, Vec2{0, 0}, 1e10))
+
+	star := NewStar2D(Vec2{-40, -40}, Vec2{0, 0}, 1)
+	fmt.Printf("%.6f\n", root.CalcAllForces(star, 0.5, WithOpeningCriterion(BmaxCriterion)))
+	// Output:
+	// {0.000563 0.000433}
+}
+
+// forceErrors returns the mean and the maximum relative error of the forces acting on the given stars calculated
+// using the tree compared to the forces calculated by summing up the forces of all the stars in the tree directly.
+// If previousAcceleration is set, the exact acceleration is given to CalcAllForces as the previous acceleration.
+func forceErrors(root *Node, stars []Star2D, theta float64, previousAcceleration bool, opts ...ForceOption) (float64, float64) {
+	all := root.GetAllStars()
+
+	meanError, maxError := 0.0, 0.0
+	for _, star := range stars {
+		want := Vec2{}
+		for _, other := range all {
+			if other != star {
+				want = want.Add(CalcForce(star, other))
+			}
+		}
+
+		starOpts := opts
+		if previousAcceleration {
+			starOpts = append(starOpts[:len(starOpts):len(starOpts)], WithPreviousAcceleration(want.Multiply(1/star.M)))
+		}
+
+		got := root.CalcAllForces(star, theta, starOpts...)
+		relativeError := math.Hypot(got.X-want.X, got.Y-want.Y) / math.Hypot(want.X, want.Y)
+		meanError += relativeError / float64(len(stars))
+		maxError = math.Max(maxError, relativeError)
+	}
+	return meanError, maxError
+}
+
+func TestNode_CalcAllForces_openingCriterion(t *testing.T) {
+
+	// the stars are placed far away from the origin, measuring the distance to the origin instead of the distance to
+	// the center of mass of a cell would accept the whole tree as a single cell
+	stars := randomStars(2000, 100, 11)
+	for i := range stars {
+		stars[i].C = stars[i].C.Add(Vec2{1e4, -1e4})
+	}
+	root, err := BuildTree(stars)
+	if err != nil {
+		t.Fatalf("BuildTree() error = %v", err)
+	}
+
+	tests := []struct {
+		name                 string
+		criterion            OpeningCriterion
+		theta                float64
+		previousAcceleration bool
+		wantMeanError        float64
+		wantMaxError         float64
+	}{
+		{
+			name:          "classic criterion opening every cell",
+			criterion:     ClassicCriterion,
+			theta:         0,
+			wantMeanError: 1e-12,
+			wantMaxError:  1e-12,
+		},
+		{
+			name:          "classic criterion using theta 0.3",
+			criterion:     ClassicCriterion,
+			theta:         0.3,
+			wantMeanError: 0.01,
+			wantMaxError:  0.1,
+		},
+		{
+			name:          "classic criterion using theta 0.5",
+			criterion:     ClassicCriterion,
+			theta:         0.5,
+			wantMeanError: 0.05,
+			wantMaxError:  1,
+		},
+		{
+			name:          "bmax criterion opening every cell",
+			criterion:     BmaxCriterion,
+			theta:         0,
+			wantMeanError: 1e-12,
+			wantMaxError:  1e-12,
+		},
+		{
+			name:          "bmax criterion using theta 0.3",
+			criterion:     BmaxCriterion,
+			theta:         0.3,
+			wantMeanError: 0.015,
+			wantMaxError:  0.15,
+		},
+		{
+			name:          "bmax criterion using theta 0.5",
+			criterion:     BmaxCriterion,
+			theta:         0.5,
+			wantMeanError: 0.06,
+			wantMaxError:  1,
+		},
+		{
+			name:                 "relative error criterion using a tolerance of 0.001",
+			criterion:            RelativeErrorCriterion,
+			theta:                0.001,
+			previousAcceleration: true,
+			wantMeanError:        0.005,
+			wantMaxError:         0.01,
+		},
+		{
+			name:                 "relative error criterion using a tolerance of 0.01",
+			criterion:            RelativeErrorCriterion,
+			theta:                0.01,
+			previousAcceleration: true,
+			wantMeanError:        0.02,
+			wantMaxError:         0.05,
+		},
+		{
+			name:          "relative error criterion without a previous acceleration",
+			criterion:     RelativeErrorCriterion,
+			theta:         0.3,
+			wantMeanError: 0.01,
+			wantMaxError:  0.1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meanError, maxError := forceErrors(root, stars[:50], tt.theta, tt.previousAcceleration,
+				WithOpeningCriterion(tt.criterion))
+			if meanError > tt.wantMeanError || maxError > tt.wantMaxError {
+				t.Errorf("Node.CalcAllForces() errors = %v %v, want at most %v %v",
+					meanError, maxError, tt.wantMeanError, tt.wantMaxError)
+			}
+		})
+	}
+
+	// the quadrupole correction reaches the same accuracy using a larger theta
+	monopoleError, _ := forceErrors(root, stars[:50], 0.3, false)
+	quadrupoleError, _ := forceErrors(root, stars[:50], 0.5, false, WithQuadrupole())
+	if quadrupoleError > monopoleError {
+		t.Errorf("Node.CalcAllForces() error with quadrupole = %v, want at most %v", quadrupoleError, monopoleError)
+	}
+}
+
+func TestNode_CalcAllForces_containingCell(t *testing.T) {
+
+	// the star shares the root with a small cluster far away from it, so the root is accepted by the size of the
+	// cell compared to the distance of its center of mass if theta is large enough
+	target := NewStar2D(Vec2{1, 1}, Vec2{0, 0}, 1)
+	cluster := []Star2D{
+		NewStar2D(Vec2{-40, -40}, Vec2{0, 0}, 1),
+		NewStar2D(Vec2{-41, -40}, Vec2{0, 0}, 1),
+		NewStar2D(Vec2{-40, -41}, Vec2{0, 0}, 1),
+	}
+	root := NewRoot(100)
+	for _, star := range append(cluster, target) {
+		if err := root.Insert(star); err != nil {
+			t.Fatalf("Node.Insert() error = %v", err)
+		}
+	}
+
+	want := Vec2{}
+	for _, star := range cluster {
+		want = want.Add(CalcForce(target, star))
+	}
+
+	for _, criterion := range []OpeningCriterion{ClassicCriterion, BmaxCriterion} {
+		for _, theta := range []float64{0.7, 1, 3, 10} {
+			got := root.CalcAllForces(target, theta, WithOpeningCriterion(criterion))
+			if got.Distance(want) > 1e-3*want.Len() {
+				t.Errorf("Node.CalcAllForces() criterion %v theta %v = %v, want %v", criterion, theta, got, want)
+			}
+		}
+	}
+}
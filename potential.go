@@ -0,0 +1,77 @@
+// potential.go calculates the potential energy of stars
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+import "math"
+
+// CalcPotential calculates the potential energy in between the stars s1 and s2. The options define how the
+// potential is softened and which unit system is used, the same way they do for CalcForce.
+func CalcPotential(s1 Star2D, s2 Star2D, opts ...ForceOption) float64 {
+	options := newForceOptions(opts)
+	return calcPotential(s1, s2, &options, options.softening(s1, s2))
+}
+
+// calcPotential calculates the potential energy in between s1 and s2 using the given options and softening length
+func calcPotential(s1 Star2D, s2 Star2D, options *forceOptions, length float64) float64 {
+	kernel := options.kernel
+	if length <= 0 {
+		kernel = NoSoftening
+	}
+
+	return -options.units.G() * s1.M * s2.M * softenedPotentialFactor(kernel, length, s2.C.Sub(s1.C).Len2())
+}
+
+// CalcPotential calculates the potential energy of the given star in the field of all the other stars in the tree
+// it is called on. The cells are opened using theta and the options the same way CalcAllForces opens them.
+func (n *BodyNode[B]) CalcPotential(star B, theta float64, opts ...ForceOption) float64 {
+	_, potential := n.CalcForceAndPotential(star, theta, opts...)
+	return potential
+}
+
+// CalcForceAndPotential calculates the force acting on the given star and its potential energy in a single walk
+// through the tree. The force is the same as the one returned by CalcAllForces using the same arguments.
+func (n *BodyNode[B]) CalcForceAndPotential(star B, theta float64, opts ...ForceOption) (Vec2, float64) {
+	options := newForceOptions(opts)
+	return n.walk(bodyStar(star), theta, &options, true)
+}
+
+// calcLeafPotential calculates the potential energy of the star in the field of a star stored in a leaf. Like in
+// calcLeafForce, a star at the same position is the star itself and doesn't contribute anything.
+func calcLeafPotential(star Star2D, leafStar Star2D, options *forceOptions) float64 {
+	if star.C == leafStar.C {
+		return 0
+	}
+	return calcPotential(star, leafStar, options, options.softening(star, leafStar))
+}
+
+// calcQuadrupolePotential calculates the potential energy of the star in the field of the quadrupole moment of the
+// node. It is the correction that has to be added to the potential energy of the total mass of the node located
+// at its center of mass.
+func (n *BodyNode[B]) calcQuadrupolePotential(star Star2D, options *forceOptions) float64 {
+
+	// define a vector pointing from the center of mass of the node to the star
+	var r Vec2 = star.C.Sub(n.CenterOfMass)
+	var distanceSquared float64 = r.Len2()
+	if distanceSquared == 0 {
+		return 0
+	}
+
+	var rqr float64 = r.Dot(n.Quadrupole.apply(r))
+	var distance5 float64 = distanceSquared * distanceSquared * math.Sqrt(distanceSquared)
+
+	return -options.units.G() / 2 * star.M * rqr / distance5
+}
@@ -0,0 +1,189 @@
+// potential_test.go provides tests for potential.go
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// CalcPotential calculates the potential energy in between two stars
+func ExampleCalcPotential() {
+	s1 := NewStar2D(Vec2{0, 0}, Vec2{0, 0}, 1)
+	s2 := NewStar2D(Vec2{3, 4}, Vec2{0, 0}, 2)
+
+	fmt.Printf("%.4f\n", CalcPotential(s1, s2, WithUnits(NBodyUnits)))
+	fmt.Printf("%.4f\n", CalcPotential(s1, s2, WithUnits(NBodyUnits), WithSoftening(PlummerSoftening, 5)))
+	// Output:
+	// -0.4000
+	// -0.2828
+}
+
+func TestCalcPotential(t *testing.T) {
+	type args struct {
+		kernel   SofteningKernel
+		length   float64
+		distance float64
+	}
+	tests := []struct {
+		name string
+		args args
+		want float64
+	}{
+		{
+			name: "no softening",
+			args: args{kernel: NoSoftening, distance: 2},
+			want: -3,
+		},
+		{
+			name: "plummer softening of coincident stars",
+			args: args{kernel: PlummerSoftening, length: 2, distance: 0},
+			want: -3,
+		},
+		{
+			name: "spline softening of coincident stars",
+			args: args{kernel: SplineSoftening, length: 2, distance: 0},
+			want: -3,
+		},
+		{
+			name: "spline softening outside of the kernel",
+			args: args{kernel: SplineSoftening, length: 1, distance: 6},
+			want: -1,
+		},
+		{
+			name: "spline softening using a length of zero",
+			args: args{kernel: SplineSoftening, length: 0, distance: 6},
+			want: -1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s1 := NewStar2D(Vec2{1, 1}, Vec2{0, 0}, 2)
+			s2 := NewStar2D(Vec2{1, 1 + tt.args.distance}, Vec2{0, 0}, 3)
+
+			units := NewNBodyUnits(1, 1/GravitationalConstant)
+			got := CalcPotential(s1, s2, WithUnits(units), WithSoftening(tt.args.kernel, tt.args.length))
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("CalcPotential() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNode_calcQuadrupolePotential(t *testing.T) {
+	root, err := BuildTree(randomStars(100, 10, 21))
+	if err != nil {
+		t.Fatalf("BuildTree() error = %v", err)
+	}
+	options := newForceOptions(nil)
+
+	// the force is the negative gradient of the potential
+	const step = 1e-4
+	for _, position := range []Vec2{{40, 0}, {-30, 25}, {3, -60}} {
+		star := NewStar2D(position, Vec2{0, 0}, 2)
+		shifted := func(dx float64, dy float64) float64 {
+			return root.calcQuadrupolePotential(NewStar2D(Vec2{position.X + dx, position.Y + dy}, Vec2{0, 0}, 2), &options)
+		}
+
+		want := root.calcQuadrupoleForce(star, &options)
+		got := Vec2{
+			X: -(shifted(step, 0) - shifted(-step, 0)) / (2 * step),
+			Y: -(shifted(0, step) - shifted(0, -step)) / (2 * step),
+		}
+		if math.Hypot(got.X-want.X, got.Y-want.Y) > 1e-6*math.Hypot(want.X, want.Y) {
+			t.Errorf("Node.calcQuadrupolePotential() gradient = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestNode_CalcForceAndPotential(t *testing.T) {
+	stars := randomStars(2000, 100, 22)
+	root, err := BuildTree(stars)
+	if err != nil {
+		t.Fatalf("BuildTree() error = %v", err)
+	}
+
+	// the direct sum returns the potentials divided by the masses of the stars
+	_, potentials := DirectSum(stars, 0)
+	_, softenedPotentials := DirectSum(stars, 0, WithSoftening(SplineSoftening, 2))
+
+	tests := []struct {
+		name       string
+		theta      float64
+		opts       []ForceOption
+		potentials []float64
+		wantError  float64
+	}{
+		{
+			name:       "opening every cell",
+			theta:      0,
+			potentials: potentials,
+			wantError:  1e-12,
+		},
+		{
+			name:       "opening every cell using spline softening",
+			theta:      0,
+			opts:       []ForceOption{WithSoftening(SplineSoftening, 2)},
+			potentials: softenedPotentials,
+			wantError:  1e-12,
+		},
+		{
+			name:       "monopoles",
+			theta:      0.5,
+			potentials: potentials,
+			wantError:  1e-2,
+		},
+		{
+			name:       "quadrupoles",
+			theta:      0.5,
+			opts:       []ForceOption{WithQuadrupole()},
+			potentials: potentials,
+			wantError:  2e-4,
+		},
+		{
+			name:       "bmax criterion",
+			theta:      0.5,
+			opts:       []ForceOption{WithOpeningCriterion(BmaxCriterion)},
+			potentials: potentials,
+			wantError:  2e-2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meanError := 0.0
+			for i, star := range stars[:100] {
+				force, potential := root.CalcForceAndPotential(star, tt.theta, tt.opts...)
+
+				if want := root.CalcAllForces(star, tt.theta, tt.opts...); force != want {
+					t.Errorf("Node.CalcForceAndPotential() force = %v, want %v", force, want)
+				}
+				if want := root.CalcPotential(star, tt.theta, tt.opts...); potential != want {
+					t.Errorf("Node.CalcForceAndPotential() potential = %v, want %v", potential, want)
+				}
+
+				want := tt.potentials[i] * star.M
+				meanError += math.Abs(potential-want) / math.Abs(want) / 100
+			}
+
+			if meanError > tt.wantError {
+				t.Errorf("Node.CalcForceAndPotential() mean potential error = %v, want at most %v",
+					meanError, tt.wantError)
+			}
+		})
+	}
+}
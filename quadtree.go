@@ -17,26 +17,61 @@
 package structs
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"math"
-	"os"
 	"os/exec"
 	"sync"
 )
 
-// Node defines a node in the tree storing the galaxy
-type Node struct {
+var (
+	// ErrZeroStar is returned when trying to insert the empty star Star3D{} that marks empty slots in a Node3D
+	ErrZeroStar = errors.New("the empty star can't be stored in the tree")
+
+	// ErrOutOfBounds is returned when a star is outside of the boundary of the tree
+	ErrOutOfBounds = errors.New("star is outside of the boundary")
+
+	// ErrDuplicatePosition is returned when a star is inserted at the position of a star all ready in the tree
+	ErrDuplicatePosition = errors.New("a star all ready exists at this position")
+
+	// ErrMaxDepthExceeded is returned when a star can only be separated from its neighbour by subdividing the tree
+	// deeper than its maximum depth and the tree does not use buckets
+	ErrMaxDepthExceeded = errors.New("maximum depth of the tree exceeded")
+
+	// ErrStarNotFound is returned when a star that should be modified is not in the tree
+	ErrStarNotFound = errors.New("star not found in the tree")
+)
+
+// Node defines a node in the tree storing the galaxy. It is a BodyNode storing stars.
+type Node = BodyNode[Star2D]
+
+// BodyNode defines a node in the tree storing bodies of the type B. The bodies of a leaf are stored in a slice, so
+// a leaf is occupied if the slice isn't empty. This way every body can be stored, even one at the origin without
+// any mass or velocity.
+type BodyNode[B Body] struct {
 	Boundary     BoundingBox // Spatial outreach of the quadtree
 	CenterOfMass Vec2        // Center of mass of the cell
-	TotalMass    float64     // Total mass of all the stars in the cell
+	TotalMass    float64     // Total mass of all the bodies in the cell
+	Quadrupole   Quadrupole  // Quadrupole moment of the cell around its center of mass
 	Depth        int         // Depth of the cell in the tree
+	Config       TreeConfig  // Configuration of the tree the cell is part of
 
-	Star Star2D // The actual star
+	Bodies []B // The bodies stored in the leaf
 
 	// NW, NE, SW, SE
-	Subtrees [4]*Node // The child subtrees
+	Subtrees [4]*BodyNode[B] // The child subtrees
+
+	bodyCount int          // Amount of bodies in the cell, maintained alongside the moments
+	mutex     sync.Mutex   // Guards the fields of the node while bodies are inserted concurrently
+	treeMutex sync.RWMutex // Guards the structure of the tree below the root Insert, Remove and Update are called on
+}
+
+// String returns the node and all its subtrees in the same way the fields of the node would be printed. The quadrupole
+// and the mutexes guarding the node are left out.
+func (n *BodyNode[B]) String() string {
+	return fmt.Sprintf("{%v %v %v %v %v %v %v}",
+		n.Boundary, n.CenterOfMass, n.TotalMass, n.Depth, n.Config, n.Bodies, n.Subtrees)
 }
 
 // NewRoot returns a pointer to a node defined as a root node. It taks the with of the BoundingBox as an argument
@@ -50,94 +85,475 @@ func NewRoot(BoundingBoxWidth float64) *Node {
 		CenterOfMass: Vec2{},
 		TotalMass:    0,
 		Depth:        0,
-		Star:         Star2D{},
 		Subtrees:     [4]*Node{},
 	}
 }
 
+// NewRootWithConfig returns a pointer to a root node just like NewRoot does, but the tree grown from that node
+// uses the given configuration.
+func NewRootWithConfig(BoundingBoxWidth float64, config TreeConfig) *Node {
+	return NewBodyRoot[Star2D](BoundingBoxWidth, config)
+}
+
+// NewRootFitting returns a pointer to a root node whose BoundingBox tightly fits all of the given bodies.
+// The tree grown from that node uses the given configuration.
+func NewRootFitting[B Body](bodies []B, config TreeConfig) *BodyNode[B] {
+	root := NewBodyRoot[B](0, config)
+	root.Boundary = NewBoundingBoxFitting(bodies)
+	return root
+}
+
 // NewNode creates a new new node using the given bounding box
 func NewNode(bounadry BoundingBox) *Node {
-	return &Node{Boundary: bounadry}
+	return NewBodyNode[Star2D](bounadry)
 }
 
-// Subdivide the tree
-func (n *Node) Subdivide() {
+// Len returns the amount of bodies stored in the tree
+func (n *BodyNode[B]) Len() int {
+	return n.bodyCount
+}
+
+// subdivided returns true if the node has subtrees
+func (n *BodyNode[B]) subdivided() bool {
+	return n.Subtrees != [4]*BodyNode[B]{}
+}
+
+// quadrantOf returns the index of the quadrant (NW, NE, SW, SE) of the node the position is in
+func (n *BodyNode[B]) quadrantOf(position Vec2) int {
+	return Star2D{C: position}.getRelativePositionInt(n.Boundary)
+}
 
-	// define new values defining the new BoundaryBoxes
-	newBoundaryWidth := n.Boundary.Width / 2
-	newBoundaryPosX := n.Boundary.Center.X + (newBoundaryWidth / 2)
-	newBoundaryPosY := n.Boundary.Center.Y + (newBoundaryWidth / 2)
-	newBoundaryNegX := n.Boundary.Center.X - (newBoundaryWidth / 2)
-	newBoundaryNegY := n.Boundary.Center.Y - (newBoundaryWidth / 2)
+// Subdivide the tree
+func (n *BodyNode[B]) Subdivide() {
 
 	// define the new Subtrees
-	n.Subtrees[0] = NewNode(BoundingBox{Vec2{newBoundaryNegX, newBoundaryPosY}, newBoundaryWidth})
-	n.Subtrees[1] = NewNode(BoundingBox{Vec2{newBoundaryPosX, newBoundaryPosY}, newBoundaryWidth})
-	n.Subtrees[2] = NewNode(BoundingBox{Vec2{newBoundaryNegX, newBoundaryNegY}, newBoundaryWidth})
-	n.Subtrees[3] = NewNode(BoundingBox{Vec2{newBoundaryPosX, newBoundaryNegY}, newBoundaryWidth})
-}
-
-// Insert inserts the given star into the Node or the tree it is called on
-func (n *Node) Insert(star Star2D) error {
-	var mutex = &sync.Mutex{}
-	mutex.Lock()
-
-	// if the subtree does not contain a node, insert the star
-	if n.Star == (Star2D{}) {
-		// if a subtree is present, insert the star into that subtree
-		if n.Subtrees != [4]*Node{} {
-			QuadrantBlocking := star.getRelativePositionInt(n.Boundary)
-			err := n.Subtrees[QuadrantBlocking].Insert(star)
-			if err != nil {
-				fmt.Println(err)
-			}
+	for quadrant := range n.Subtrees {
+		n.Subtrees[quadrant] = NewBodyNode[B](n.Boundary.Quadrant(quadrant))
+	}
 
-			// directly insert the star into the node
-		} else {
-			n.Star = star
-			return nil
+	// the subtrees are one level deeper and share the configuration of the tree
+	for _, subtree := range n.Subtrees {
+		subtree.Depth = n.Depth + 1
+		subtree.Config = n.Config
+	}
+}
+
+// expandTowards doubles the BoundingBox of the root node it is called on towards the given point until the point
+// is inside of it. The existing tree becomes one of the Subtrees of the grown root without reinserting any of its
+// bodies. If the tree is not configured to AutoExpand or can't grow, ErrOutOfBounds is returned.
+func (n *BodyNode[B]) expandTowards(point Vec2) error {
+	if n.Config.AutoExpand == false || n.Depth != 0 {
+		return ErrOutOfBounds
+	}
+
+	// a tree without a width or a point that isn't finite can never be reached by doubling the boundary
+	if n.Boundary.Width <= 0 || math.IsInf(n.Boundary.Width, 0) || point.IsFinite() == false {
+		return ErrOutOfBounds
+	}
+
+	for n.Boundary.Contains(point) == false {
+		if err := n.expand(point); err != nil {
+			return err
 		}
 
-		// Move the star blocking the slot into it's subtree using a recursive call on this function
-		// and add the star to the slot
+		if math.IsInf(n.Boundary.Width, 0) {
+			return ErrOutOfBounds
+		}
+	}
+
+	return nil
+}
+
+// expand doubles the BoundingBox of the node towards the given point and moves the previous content of the node
+// into the subtree opposite of the point. If the bodies on the edges of the previous boundary can't be moved into
+// their new subtrees, the node is left unchanged and the error is returned.
+func (n *BodyNode[B]) expand(point Vec2) error {
+
+	// move the whole content of the node into a new node
+	previous := &BodyNode[B]{
+		Boundary:     n.Boundary,
+		CenterOfMass: n.CenterOfMass,
+		TotalMass:    n.TotalMass,
+		Quadrupole:   n.Quadrupole,
+		Depth:        n.Depth,
+		Config:       n.Config,
+		Bodies:       n.Bodies,
+		Subtrees:     n.Subtrees,
+		bodyCount:    n.bodyCount,
+	}
+	previous.setDepth(n.Depth + 1)
+
+	// grow the boundary towards the point
+	halfWidth := n.Boundary.Width / 2
+	center := n.Boundary.Center
+	if point.X < n.Boundary.Center.X {
+		center.X -= halfWidth
+	} else {
+		center.X += halfWidth
+	}
+	if point.Y < n.Boundary.Center.Y {
+		center.Y -= halfWidth
 	} else {
-		// if the node does not all ready have child nodes, subdivide it
-		if n.Subtrees == ([4]*Node{}) {
-			n.Subdivide()
+		center.Y += halfWidth
+	}
+
+	n.Boundary = NewBoundingBox(center, n.Boundary.Width*2)
+	n.Bodies = nil
+	n.Subtrees = [4]*BodyNode[B]{}
+	n.Subdivide()
+
+	// replace the subtree covering the previous boundary with the previous content
+	quadrant := n.quadrantOf(previous.Boundary.Center)
+	n.Subtrees[quadrant] = previous
+
+	// bodies on the edges of the previous boundary facing the point lie on the center lines of the grown boundary,
+	// so they belong to the neighbouring quadrants and are moved there
+	var misplaced []B
+	if point.X < previous.Boundary.Center.X || point.Y < previous.Boundary.Center.Y {
+		for _, body := range previous.GetAllBodies() {
+			if n.quadrantOf(body.Position()) != quadrant {
+				misplaced = append(misplaced, body)
+			}
+		}
+	}
+
+	// the bodies are inserted into their new quadrants before they are removed from the previous content, so the
+	// previous content can be restored if they don't fit in there
+	for _, body := range misplaced {
+		if err := n.insert(body); err != nil {
+			n.Boundary = previous.Boundary
+			n.CenterOfMass = previous.CenterOfMass
+			n.TotalMass = previous.TotalMass
+			n.Quadrupole = previous.Quadrupole
+			n.Bodies = previous.Bodies
+			n.Subtrees = previous.Subtrees
+			n.bodyCount = previous.bodyCount
+			n.setDepth(n.Depth)
+			return err
 		}
+	}
+
+	for _, body := range misplaced {
+		previous.remove(body)
+	}
+
+	n.collapse()
+	n.updateMoments()
+	return nil
+}
+
+// setDepth sets the depth of the node to the given depth and the depth of all its subtrees accordingly
+func (n *BodyNode[B]) setDepth(depth int) {
+	n.Depth = depth
+	for _, subtree := range n.Subtrees {
+		if subtree != nil {
+			subtree.setDepth(depth + 1)
+		}
+	}
+}
+
+// Insert inserts the given body into the Node or the tree it is called on. The TotalMass and the CenterOfMass of
+// all the nodes the body passes on its way down are updated.
+// If the body is outside of the root of a tree configured to AutoExpand, the root grows until the body fits.
+// Insert can be called from multiple goroutines at the same time as long as it is always called on the root of
+// the tree. Every node is locked on its own, so bodies heading into different subtrees don't block each other.
+// If the body can't be inserted, an error wrapping one of ErrOutOfBounds, ErrDuplicatePosition or
+// ErrMaxDepthExceeded is returned and the tree is left unchanged.
+func (n *BodyNode[B]) Insert(body B) error {
+	position := body.Position()
+
+	// make sure the body is inside of the tree. Growing the tree changes the whole structure of the tree, so no
+	// other body can be inserted at the same time
+	n.treeMutex.RLock()
+	if n.Boundary.Contains(position) == false {
+		n.treeMutex.RUnlock()
+
+		n.treeMutex.Lock()
+		var err error
+		if n.Boundary.Contains(position) == false {
+			err = n.expandTowards(position)
+		}
+		n.treeMutex.Unlock()
 
-		// Insert the blocking star into it's subtree
-		QuadrantBlocking := n.Star.getRelativePositionInt(n.Boundary)
-		err := n.Subtrees[QuadrantBlocking].Insert(n.Star)
 		if err != nil {
-			fmt.Println(err)
+			return fmt.Errorf("could not insert star (%f, %f): %w", position.X, position.Y, err)
 		}
-		n.Star = Star2D{}
 
-		// Insert the blocking star into it's subtree
-		QuadrantBlockingNew := star.getRelativePositionInt(n.Boundary)
-		err = n.Subtrees[QuadrantBlockingNew].Insert(star)
+		// the tree only grows, so the body is still inside of it after locking it again
+		n.treeMutex.RLock()
+	}
+	defer n.treeMutex.RUnlock()
+
+	err := n.insert(body)
+	if err != nil {
+		return fmt.Errorf("could not insert star (%f, %f): %w", position.X, position.Y, err)
+	}
+
+	return nil
+}
+
+// insert recursively inserts the body into the node it is called on. The node is locked while it is modified.
+func (n *BodyNode[B]) insert(body B) error {
+	n.mutex.Lock()
+
+	// if a subtree is present, insert the body into that subtree
+	if n.subdivided() && len(n.Bodies) == 0 {
+		subtree := n.Subtrees[n.quadrantOf(body.Position())]
+
+		// the node is unlocked while the body moves down the subtree, so other bodies can pass the node meanwhile
+		n.mutex.Unlock()
+		err := subtree.insert(body)
 		if err != nil {
-			fmt.Println(err)
+			return err
+		}
+
+		n.mutex.Lock()
+		n.updateMoments()
+		n.mutex.Unlock()
+		return nil
+	}
+
+	// the node is modified directly, so it stays locked until the body is inserted
+	defer n.mutex.Unlock()
+
+	// two bodies at the same position can't be separated, no matter how often the node is subdivided
+	bodies := n.Bodies
+	for _, leafBody := range bodies {
+		if leafBody.Position() == body.Position() {
+			return ErrDuplicatePosition
+		}
+	}
+
+	if n.subdivided() == false {
+
+		// directly insert the body into the node if there is some space left
+		if len(bodies) < n.Config.capacity() {
+			n.Bodies = append(bodies[:len(bodies):len(bodies)], body)
+			n.updateMoments()
+			return nil
+		}
+
+		// leaves at the maximum depth can't be subdivided, so the body is stored in the bucket
+		if n.Depth >= n.Config.maxDepth() {
+			if n.Config.buckets() == false {
+				return ErrMaxDepthExceeded
+			}
+			n.Bodies = append(bodies[:len(bodies):len(bodies)], body)
+			n.updateMoments()
+			return nil
+		}
+
+		n.Subdivide()
+	}
+
+	// Move the bodies blocking the slot into their subtrees
+	n.Bodies = nil
+	for _, blockingBody := range bodies {
+		err := n.Subtrees[n.quadrantOf(blockingBody.Position())].insert(blockingBody)
+		if err != nil {
+			n.collapse()
+			return err
+		}
+	}
+
+	// Insert the new body into it's subtree
+	err := n.Subtrees[n.quadrantOf(body.Position())].insert(body)
+	if err != nil {
+		// undo the subdivision, so the tree is left unchanged
+		n.collapse()
+		return err
+	}
+
+	// the subtrees changed, so the moments of the node have to be updated
+	n.updateMoments()
+
+	return nil
+}
+
+// Remove removes the given body from the tree it is called on. It returns true if the body was found and
+// removed. Subtrees that end up holding no more bodies than a single leaf can hold are merged back into their
+// parent and the TotalMass and CenterOfMass of every node on the path to the body are updated on the way back up.
+func (n *BodyNode[B]) Remove(body B) bool {
+	n.treeMutex.Lock()
+	defer n.treeMutex.Unlock()
+
+	return n.remove(body)
+}
+
+// remove recursively searches the body using its relative position and removes it from the tree
+func (n *BodyNode[B]) remove(body B) bool {
+
+	// if the body is stored directly in the node, remove it
+	for i, leafBody := range n.Bodies {
+		if leafBody == body {
+			n.Bodies = append(n.Bodies[:i:i], n.Bodies[i+1:]...)
+			n.collapse()
+			n.updateMoments()
+			return true
+		}
+	}
+
+	// if the node does not have any subtrees, the body is not in the tree
+	if n.subdivided() == false {
+		return false
+	}
+
+	// search the body in the subtree it should be in
+	subtree := n.Subtrees[n.quadrantOf(body.Position())]
+	if subtree == nil || subtree.remove(body) == false {
+		return false
+	}
+
+	n.collapse()
+	n.updateMoments()
+	return true
+}
+
+// contains recursively searches the body using its relative position and returns true if it is in the tree
+func (n *BodyNode[B]) contains(body B) bool {
+	for _, leafBody := range n.Bodies {
+		if leafBody == body {
+			return true
+		}
+	}
+
+	// if the node does not have any subtrees, the body is not in the tree
+	if n.subdivided() == false {
+		return false
+	}
+
+	subtree := n.Subtrees[n.quadrantOf(body.Position())]
+	return subtree != nil && subtree.contains(body)
+}
+
+// collapse merges the subtrees of the node back into the node if all of them are leaves and together hold no more
+// bodies than a single leaf can hold
+func (n *BodyNode[B]) collapse() {
+	bodies := n.Bodies
+
+	for _, subtree := range n.Subtrees {
+		if subtree == nil {
+			continue
+		}
+
+		// subtrees containing subtrees on their own can't be merged
+		if subtree.subdivided() {
+			return
+		}
+
+		bodies = append(bodies[:len(bodies):len(bodies)], subtree.Bodies...)
+	}
+
+	if len(bodies) > n.Config.capacity() {
+		return
+	}
+
+	n.Bodies = bodies
+	n.Subtrees = [4]*BodyNode[B]{}
+}
+
+// Update moves the body oldBody stored in the tree to the position of newBody without rebuilding the tree.
+// If the new position is still inside of the leaf the body is stored in, only the mass moments are updated.
+// If it isn't, the body is removed and reinserted starting at the nearest node whose Boundary contains both
+// the old and the new position.
+func (n *BodyNode[B]) Update(oldBody B, newBody B) error {
+	position := oldBody.Position()
+
+	n.treeMutex.Lock()
+	defer n.treeMutex.Unlock()
+
+	// the root only grows towards the new position if there is a body to move
+	if n.contains(oldBody) == false {
+		return fmt.Errorf("could not update star (%f, %f): %w", position.X, position.Y, ErrStarNotFound)
+	}
+
+	if n.Boundary.Contains(newBody.Position()) == false {
+		if err := n.expandTowards(newBody.Position()); err != nil {
+			return fmt.Errorf("could not update star (%f, %f): %w", position.X, position.Y, err)
 		}
-		star = Star2D{}
 	}
 
-	mutex.Unlock()
+	found, err := n.update(oldBody, newBody)
+	if err != nil {
+		return fmt.Errorf("could not update star (%f, %f): %w", position.X, position.Y, err)
+	}
+	if found == false {
+		return fmt.Errorf("could not update star (%f, %f): %w", position.X, position.Y, ErrStarNotFound)
+	}
 
-	// fmt.Println("Done inserting %v, the tree looks like this: %v", star, n)
 	return nil
 }
 
+// update recursively follows the path of the old and the new body as long as both end up in the same
+// quadrant and moves the body where the paths split up
+func (n *BodyNode[B]) update(oldBody B, newBody B) (bool, error) {
+
+	// the body is stored in this node and the new position is inside of it, so the body can simply be replaced
+	for i, leafBody := range n.Bodies {
+		if leafBody != oldBody {
+			continue
+		}
+
+		for j, otherBody := range n.Bodies {
+			if j != i && otherBody.Position() == newBody.Position() {
+				return true, ErrDuplicatePosition
+			}
+		}
+
+		n.Bodies = append([]B{}, n.Bodies...)
+		n.Bodies[i] = newBody
+		n.updateMoments()
+		return true, nil
+	}
+
+	// if the node does not have any subtrees, the body is not in the tree
+	if n.subdivided() == false {
+		return false, nil
+	}
+
+	oldQuadrant := n.quadrantOf(oldBody.Position())
+	newQuadrant := n.quadrantOf(newBody.Position())
+	if n.Subtrees[oldQuadrant] == nil {
+		return false, nil
+	}
+
+	// if both bodies are in the same quadrant, descend further into the tree
+	if oldQuadrant == newQuadrant {
+		found, err := n.Subtrees[oldQuadrant].update(oldBody, newBody)
+		if found == true {
+			n.updateMoments()
+		}
+		return found, err
+	}
+
+	// the body leaves the quadrant, so it is moved from one subtree into the other. If the new body can't be
+	// inserted, the old one is put back to leave the tree unchanged
+	if n.Subtrees[oldQuadrant].remove(oldBody) == false {
+		return false, nil
+	}
+	err := n.Subtrees[newQuadrant].insert(newBody)
+	if err != nil {
+		_ = n.Subtrees[oldQuadrant].insert(oldBody)
+		return true, err
+	}
+
+	n.collapse()
+	n.updateMoments()
+	return true, nil
+}
+
 // GenForestTree draws the subtree it is called on. If there is a star inside of the root node, the node is drawn
 // The method returns a string depicting the tree in latex forest structure
-func (n Node) GenForestTree(node *Node) string {
+func (n *BodyNode[B]) GenForestTree(node *BodyNode[B]) string {
 
 	returnstring := "["
 
-	// if there is a star in the node, add the stars coordinates to the return string
-	if n.Star != (Star2D{}) {
-		returnstring += fmt.Sprintf("%.0f %.0f", n.Star.C.X, n.Star.C.Y)
+	// if there are bodies in the node, add the bodies coordinates to the return string
+	for i, body := range n.Bodies {
+		if i > 0 {
+			returnstring += "; "
+		}
+		returnstring += fmt.Sprintf("%.0f %.0f", body.Position().X, body.Position().Y)
 	}
 
 	// iterate over all the subtrees and call the GenForestTree method on the subtrees containing children
@@ -157,7 +573,7 @@ func (n Node) GenForestTree(node *Node) string {
 
 // DrawTreeLaTeX writes the tree it is called on to a texfile defined by the outpath parameter and
 // calls lualatex to build the tex-file
-func (n Node) DrawTreeLaTeX(outpath string) {
+func (n *BodyNode[B]) DrawTreeLaTeX(outpath string) {
 	// define all the stuff in front of the tree
 	preamble := `\documentclass{article}
 \usepackage{tikz}
@@ -180,7 +596,7 @@ for tree={,draw, s sep+=0.25em}
 `
 
 	// combine all the strings
-	data := []byte(fmt.Sprintf("%s%s%s", preamble, n.GenForestTree(&n), poststring))
+	data := []byte(fmt.Sprintf("%s%s%s", preamble, n.GenForestTree(n), poststring))
 
 	// write them to a file
 	writeerr := ioutil.WriteFile(outpath, data, 0644)
@@ -196,126 +612,67 @@ for tree={,draw, s sep+=0.25em}
 	}
 }
 
-// GetAllStars returns all the stars in the tree it is called on in an array
-func (n Node) GetAllStars() []Star2D {
+// GetAllBodies returns all the bodies in the tree it is called on in an array
+func (n *BodyNode[B]) GetAllBodies() []B {
 
-	// define a list to store the stars
-	listOfNodes := []Star2D{}
+	// define a list to store the bodies
+	listOfNodes := []B{}
 
-	// if there is a star in the node, append the star to the list
-	if n.Star != (Star2D{}) {
-		listOfNodes = append(listOfNodes, n.Star)
-	}
+	// if there are bodies in the node, append the bodies to the list
+	listOfNodes = append(listOfNodes, n.Bodies...)
 
 	// iterate over all the subtrees
 	for i := 0; i < len(n.Subtrees); i++ {
 		if n.Subtrees[i] != nil {
 
-			// insert all the stars from the subtrees into the list of nodes
-			for _, star := range n.Subtrees[i].GetAllStars() {
-				listOfNodes = append(listOfNodes, star)
-			}
+			// insert all the bodies from the subtrees into the list of nodes
+			listOfNodes = append(listOfNodes, n.Subtrees[i].GetAllBodies()...)
 		}
 	}
 
 	return listOfNodes
 }
 
-// CalcCenterOfMass calculates the center of mass for every node in the tree
-func (n *Node) calcCenterOfMass() Vec2 {
-
-	nominatorX := 0.0
-	denominatorX := 0.0
-
-	nominatorY := 0.0
-	denominatorY := 0.0
-
-	// if the subtrees are not empty
-	if n.Subtrees != ([4]*Node{}) {
-		for _, star := range n.Subtrees {
-			fmt.Println(star)
-		}
-		for i := 0; i < len(n.Subtrees); i++ {
-			nominatorX += n.Subtrees[i].calcCenterOfMass().X * n.Subtrees[i].TotalMass
-			denominatorX += n.Subtrees[i].TotalMass
-
-			nominatorY += n.Subtrees[i].calcCenterOfMass().Y * n.Subtrees[i].TotalMass
-			denominatorY += n.Subtrees[i].TotalMass
-		}
-	}
-
-	if n.Star != (Star2D{}) {
-		n.CenterOfMass = n.Star.C
-		fmt.Println(n.Star)
-	}
-
-	comX := nominatorX / denominatorX
-	comY := nominatorY / denominatorY
-
-	n.CenterOfMass = Vec2{comX, comY}
-
-	return n.CenterOfMass
+// GetAllStars returns all the stars in the tree it is called on in an array. It is the same as GetAllBodies.
+func (n *BodyNode[B]) GetAllStars() []B {
+	return n.GetAllBodies()
 }
 
-// CalcCenterOfMass calculates the center of mass for every node in the tree
-func (n *Node) CalcCenterOfMass() Vec2 {
-	tree := n.GenForestTree(n)
-	fmt.Println(tree)
-	centerOfMass := n.calcCenterOfMass()
-	return centerOfMass
+// CalcAllForces calculates the force acting in between the given body and all the other bodies using the given
+// theta. It gets all the other bodies from the root node it is called on. The options define how the force of the
+// cells accepted by the opening criterion is approximated. Bodies that aren't stars are passed to the adaptive
+// softening as stars using their position and mass.
+func (n *BodyNode[B]) CalcAllForces(body B, theta float64, opts ...ForceOption) Vec2 {
+	options := newForceOptions(opts)
+	return n.calcAllForces(bodyStar(body), theta, &options)
 }
 
-// CalcTotalMass calculates the total mass for every node in the tree
-func (n *Node) CalcTotalMass() float64 {
-
-	// if the subtrees are not empty
-	if n.Subtrees != ([4]*Node{}) {
-		for i := 0; i < len(n.Subtrees); i++ {
-			n.TotalMass += n.Subtrees[i].CalcTotalMass()
-		}
-	}
-
-	// if the star in the subtree is not empty
-	if n.Star != (Star2D{}) {
-		n.TotalMass += n.Star.M
-	}
-
-	return n.TotalMass
+// calcAllForces calculates the force acting on the given star recursively using the given options
+func (n *BodyNode[B]) calcAllForces(star Star2D, theta float64, options *forceOptions) Vec2 {
+	force, _ := n.walk(star, theta, options, false)
+	return force
 }
 
-// CalcAllForces calculates the force acting in between the given star and all the other stars using the given theta.
-// It gets all the other stars from the root node it is called on
-func (n Node) CalcAllForces(star Star2D, theta float64) Vec2 {
-	log.SetOutput(os.Stderr)
+// walk calculates the force acting on the given star recursively using the given options. If withPotential is
+// true, the potential energy of the star is accumulated along the way, otherwise the returned potential is 0.
+func (n *BodyNode[B]) walk(star Star2D, theta float64, options *forceOptions, withPotential bool) (Vec2, float64) {
 
-	// initialize a variable storing the overall force
+	// initialize the variables storing the overall force and potential energy
 	var localForce Vec2 = Vec2{}
-
-	// calculate the local theta
-	var tmpX float64 = math.Pow(star.C.X-n.Star.C.X, 2)
-	var tmpY float64 = math.Pow(star.C.Y-n.Star.C.Y, 2)
-	var distance float64 = math.Sqrt(tmpX + tmpY)
-
-	var localtheta float64 = n.Boundary.Width / distance
+	var localPotential float64 = 0
 
 	// if the subtree is not empty...
-	if n.Subtrees != ([4]*Node{}) {
+	if n.subdivided() {
 
-		// if the local theta is smaller than the given theta threshold...
-		if localtheta < theta {
+		// if the node is accepted by the opening criterion...
+		if n.accept(star, theta, options) {
 			// don't recurse further into the tree
 			// calculate the forces in between the star and the node
 
 			// define a new star using the center of mass of the new stars
 			nodeStar := Star2D{
-				C: Vec2{
-					X: n.CenterOfMass.X,
-					Y: n.CenterOfMass.Y,
-				},
-				V: Vec2{
-					X: 0,
-					Y: 0,
-				},
+				C: n.CenterOfMass,
+				V: Vec2{},
 				M: n.TotalMass,
 			}
 
@@ -323,56 +680,114 @@ func (n Node) CalcAllForces(star Star2D, theta float64) Vec2 {
 			if star != nodeStar {
 
 				// calculate the force on the individual star
-				force := CalcForce(star, nodeStar)
-				localForce.X += force.X
-				localForce.Y += force.Y
+				localForce = localForce.Add(calcForce(star, nodeStar, options, options.softening(star)))
+				if withPotential {
+					localPotential += calcPotential(star, nodeStar, options, options.softening(star))
+				}
+
+				// correct the force using the quadrupole moment of the node
+				if options.quadrupole {
+					localForce = localForce.Add(n.calcQuadrupoleForce(star, options))
+					if withPotential {
+						localPotential += n.calcQuadrupolePotential(star, options)
+					}
+				}
 			}
 
-			// the local theta is bigger than the given theta -> recurse deeper
+			// the node has to be opened -> recurse deeper
 		} else {
 
 			// iterate over all the subtrees
 			for i := 0; i < len(n.Subtrees); i++ {
-				force := n.Subtrees[i].CalcAllForces(star, theta)
-				localForce.X += force.X
-				localForce.Y += force.Y
+				force, potential := n.Subtrees[i].walk(star, theta, options, withPotential)
+				localForce = localForce.Add(force)
+				localPotential += potential
 			}
 		}
 
 		// if the subtree is empty
 	} else {
 
-		// make sure the star in the subtree is not empty
-		if n.Star != (Star2D{}) {
-
-			// if the star is not the star on which the forces should be calculated
-			if star != n.Star {
-
-				// calculate the forces acting on the star
-				force := CalcForce(star, n.Star)
-				localForce.X += force.X
-				localForce.Y += force.Y
+		// iterate over all the bodies stored in the node
+		for _, body := range n.Bodies {
+			leafStar := bodyStar(body)
+			localForce = localForce.Add(calcLeafForce(star, leafStar, options))
+			if withPotential {
+				localPotential += calcLeafPotential(star, leafStar, options)
 			}
 		}
 	}
 
-	// return the overall acting force
-	return localForce
+	// return the overall acting force and potential energy
+	return localForce, localPotential
+}
+
+// calcLeafForce calculates the force exerted on the star by a star stored in a leaf. A star at the same position
+// is the star on which the forces should be calculated itself (stars in a tree never share a position), so it
+// doesn't exert any force.
+func calcLeafForce(star Star2D, leafStar Star2D, options *forceOptions) Vec2 {
+	if star.C == leafStar.C {
+		return Vec2{}
+	}
+	return calcForce(star, leafStar, options, options.softening(star, leafStar))
+}
+
+// calcQuadrupoleForce calculates the force exerted on the star by the quadrupole moment of the node. It is the
+// correction that has to be added to the force exerted by the total mass of the node located at its center of mass.
+func (n *BodyNode[B]) calcQuadrupoleForce(star Star2D, options *forceOptions) Vec2 {
+	return quadrupoleForce(n.Quadrupole, n.CenterOfMass, star, options)
+}
+
+// quadrupoleForce calculates the force exerted on the star by the given quadrupole moment of a cell around its
+// center of mass
+func quadrupoleForce(quadrupole Quadrupole, centerOfMass Vec2, star Star2D, options *forceOptions) Vec2 {
+	G := options.units.G()
+
+	// define a vector pointing from the center of mass of the node to the star
+	var r Vec2 = star.C.Sub(centerOfMass)
+	var distanceSquared float64 = r.Len2()
+	if distanceSquared == 0 {
+		return Vec2{}
+	}
+
+	// the acceleration is the negative gradient of the potential -G/2 * (r Q r) / |r|^5
+	var qr Vec2 = quadrupole.apply(r)
+	var rqr float64 = r.Dot(qr)
+	var distance5 float64 = distanceSquared * distanceSquared * math.Sqrt(distanceSquared)
+
+	var acceleration Vec2 = qr.Multiply(G / distance5).Sub(r.Multiply(G * 2.5 * rqr / (distance5 * distanceSquared)))
+
+	// return the force exerted on the star by the quadrupole
+	return acceleration.Multiply(star.M)
 }
 
-// CalcForce calculates the force exerted on s1 by s2 and returns a vector representing that force
-func CalcForce(s1 Star2D, s2 Star2D) Vec2 {
-	G := 6.6726 * math.Pow(10, -11)
+// CalcForce calculates the force exerted on s1 by s2 and returns a vector representing that force. The options
+// define how the force is softened and which unit system is used.
+func CalcForce(s1 Star2D, s2 Star2D, opts ...ForceOption) Vec2 {
+	options := newForceOptions(opts)
+	return calcForce(s1, s2, &options, options.softening(s1, s2))
+}
+
+// calcForce calculates the force exerted on s1 by s2 using the given options and softening length
+func calcForce(s1 Star2D, s2 Star2D, options *forceOptions, length float64) Vec2 {
+	G := options.units.G()
+
+	// soften the force if a softening kernel is used
+	if options.kernel != NoSoftening && length > 0 {
+		var vector Vec2 = s2.C.Sub(s1.C)
+		var factor float64 = softenedForceFactor(options.kernel, length, vector.Len2())
+		return vector.Multiply(G * s1.M * s2.M * factor)
+	}
+
+	// define a vector pointing from s1 to s2
+	var vector Vec2 = s2.C.Sub(s1.C)
 
 	// calculate the force acting
 	var combinedMass float64 = s1.M * s2.M
-	var distance float64 = math.Sqrt(math.Pow(math.Abs(s1.C.X-s2.C.X), 2) + math.Pow(math.Abs(s1.C.Y-s2.C.Y), 2))
-
-	var scalar float64 = G * ((combinedMass) / math.Pow(distance, 2))
+	var scalar float64 = G * combinedMass / vector.Len2()
 
 	// define a unit vector pointing from s1 to s2
-	var vector Vec2 = Vec2{s2.C.X - s1.C.X, s2.C.Y - s1.C.Y}
-	var UnitVector Vec2 = Vec2{vector.X / distance, vector.Y / distance}
+	var UnitVector Vec2 = vector.Normalize()
 
 	// multiply the vector with the force to get a vector representing the force acting
 	var force Vec2 = UnitVector.Multiply(scalar)
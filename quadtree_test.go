@@ -17,8 +17,11 @@
 package structs
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"reflect"
+	"sync"
 	"testing"
 )
 
@@ -27,7 +30,7 @@ import (
 func ExampleNewRoot() {
 	root := NewRoot(100)
 	fmt.Printf("%v\n", root)
-	// Output: &{{{0 0} 100} {0 0} 0 0 {{0 0} {0 0} 0} [<nil> <nil> <nil> <nil>]}
+	// Output: {{{0 0} 100} {0 0} 0 0 {0 0 false} [] [<nil> <nil> <nil> <nil>]}
 }
 
 func TestNewRoot(t *testing.T) {
@@ -58,18 +61,7 @@ func TestNewRoot(t *testing.T) {
 				},
 				TotalMass: 0,
 				Depth:     0,
-				Star: Star2D{
-					C: Vec2{
-						X: 0,
-						Y: 0,
-					},
-					V: Vec2{
-						X: 0,
-						Y: 0,
-					},
-					M: 0,
-				},
-				Subtrees: [4]*Node{},
+				Subtrees:  [4]*Node{},
 			},
 		},
 	}
@@ -82,6 +74,422 @@ func TestNewRoot(t *testing.T) {
 	}
 }
 
+// The example below creates a new root node using a configuration that stops subdividing the tree at a depth of 2.
+// The leaves at that depth store all further stars in their bucket.
+func ExampleNewRootWithConfig() {
+	root := NewRootWithConfig(100, NewTreeConfig(2, 1))
+
+	// There will be no error handling here, we'll assume that everything goes right..
+	_ = root.Insert(NewStar2D(Vec2{30, 30}, Vec2{0, 0}, 10))
+	_ = root.Insert(NewStar2D(Vec2{31, 31}, Vec2{0, 0}, 10))
+	_ = root.Insert(NewStar2D(Vec2{32, 32}, Vec2{0, 0}, 10))
+
+	fmt.Println(root.GenForestTree(root))
+	fmt.Println(root.Subtrees[1].Subtrees[1].Depth)
+	// Output:
+	// [[[][][][]][[[][][][]][30 30; 31 31; 32 32[][][][]][[][][][]][[][][][]]][[][][][]][[][][][]]]
+	// 2
+}
+
+// Insert a star outside of the bounding box of a tree configured to grow automatically.
+// The root doubles its width towards the star and the previous tree becomes its south west subtree.
+func ExampleNode_Insert_autoExpand() {
+	root := NewRootWithConfig(100, TreeConfig{AutoExpand: true})
+
+	// There will be no error handling here, we'll assume that everything goes right..
+	_ = root.Insert(NewStar2D(Vec2{10, 20}, Vec2{0, 0}, 10))
+	_ = root.Insert(NewStar2D(Vec2{-10, -20}, Vec2{0, 0}, 10))
+	_ = root.Insert(NewStar2D(Vec2{120, 80}, Vec2{0, 0}, 10))
+
+	fmt.Println(root.Boundary)
+	fmt.Println(root.GenForestTree(root))
+	// Output:
+	// {{50 50} 200}
+	// [[[][][][]][120 80[][][][]][[[][][][]][10 20[][][][]][-10 -20[][][][]][[][][][]]][[][][][]]]
+}
+
+func TestNode_Insert_autoExpand(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       TreeConfig
+		star         Star2D
+		wantErr      error
+		wantBoundary BoundingBox
+	}{
+		{
+			name:         "Star outside of a tree that can't grow",
+			config:       TreeConfig{},
+			star:         NewStar2D(Vec2{60, 0}, Vec2{0, 0}, 10),
+			wantErr:      ErrOutOfBounds,
+			wantBoundary: BoundingBox{Vec2{0, 0}, 100},
+		},
+		{
+			name:         "Star slightly outside of the tree",
+			config:       TreeConfig{AutoExpand: true},
+			star:         NewStar2D(Vec2{-60, 10}, Vec2{0, 0}, 10),
+			wantBoundary: BoundingBox{Vec2{-50, 50}, 200},
+		},
+		{
+			name:         "Star far outside of the tree",
+			config:       TreeConfig{AutoExpand: true},
+			star:         NewStar2D(Vec2{1000, -1000}, Vec2{0, 0}, 10),
+			wantBoundary: BoundingBox{Vec2{750, -750}, 1600},
+		},
+		{
+			name:         "Star at an infinite position",
+			config:       TreeConfig{AutoExpand: true},
+			star:         NewStar2D(Vec2{math.Inf(1), 0}, Vec2{0, 0}, 10),
+			wantErr:      ErrOutOfBounds,
+			wantBoundary: BoundingBox{Vec2{0, 0}, 100},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := NewRootWithConfig(100, tt.config)
+			stars := []Star2D{
+				NewStar2D(Vec2{10, 20}, Vec2{0, 0}, 10),
+				NewStar2D(Vec2{-10, -20}, Vec2{0, 0}, 10),
+				NewStar2D(Vec2{12, 22}, Vec2{0, 0}, 10),
+			}
+			for _, star := range stars {
+				if err := n.Insert(star); err != nil {
+					t.Fatalf("Node.Insert() error = %v", err)
+				}
+			}
+			subtree := n.Subtrees[1]
+
+			if err := n.Insert(tt.star); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Node.Insert() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if n.Boundary != tt.wantBoundary {
+				t.Errorf("Node.Boundary = %v, want %v", n.Boundary, tt.wantBoundary)
+			}
+			if tt.wantErr == nil {
+				stars = append(stars, tt.star)
+			}
+			if got := n.GetAllStars(); len(got) != len(stars) {
+				t.Errorf("Node.GetAllStars() = %v, want %v", got, stars)
+			}
+
+			// the previous subtrees are reused instead of being rebuilt
+			found := false
+			var walk func(node *Node)
+			walk = func(node *Node) {
+				if node == subtree {
+					found = true
+					if node.Depth == 0 && n.Boundary.Width > 100 {
+						t.Errorf("Node.Depth of a moved subtree = %v", node.Depth)
+					}
+				}
+				for _, child := range node.Subtrees {
+					if child != nil {
+						walk(child)
+					}
+				}
+			}
+			walk(n)
+			if found == false {
+				t.Errorf("the previous subtree is not part of the grown tree")
+			}
+		})
+	}
+}
+
+func TestNode_Insert_autoExpandEdge(t *testing.T) {
+	tests := []struct {
+		name string
+		star Star2D
+	}{
+		{
+			name: "Grow towards the south west",
+			star: NewStar2D(Vec2{-60, -60}, Vec2{0, 0}, 10),
+		},
+		{
+			name: "Grow towards the north west",
+			star: NewStar2D(Vec2{-60, 60}, Vec2{0, 0}, 10),
+		},
+		{
+			name: "Grow towards the south east",
+			star: NewStar2D(Vec2{60, -60}, Vec2{0, 0}, 10),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := NewRootWithConfig(100, TreeConfig{AutoExpand: true})
+
+			// the stars are on the edges of the boundary, so they lie on the center lines of the grown boundary
+			stars := []Star2D{
+				NewStar2D(Vec2{-50, 10}, Vec2{0, 0}, 10),
+				NewStar2D(Vec2{10, -50}, Vec2{0, 0}, 20),
+				NewStar2D(Vec2{-50, -50}, Vec2{0, 0}, 30),
+				NewStar2D(Vec2{50, 50}, Vec2{0, 0}, 40),
+				NewStar2D(Vec2{20, 20}, Vec2{0, 0}, 50),
+			}
+			for _, star := range append(stars, tt.star) {
+				if err := n.Insert(star); err != nil {
+					t.Fatalf("Node.Insert() error = %v", err)
+				}
+			}
+
+			// the moments maintained while growing are the same as the ones calculated from scratch
+			totalMass, centerOfMass, quadrupole := n.TotalMass, n.CenterOfMass, n.Quadrupole
+			n.ComputeMoments()
+			if n.TotalMass != totalMass || n.CenterOfMass != centerOfMass || n.Quadrupole != quadrupole {
+				t.Errorf("Node moments = %v %v %v, want %v %v %v",
+					totalMass, centerOfMass, quadrupole, n.TotalMass, n.CenterOfMass, n.Quadrupole)
+			}
+
+			// every star can still be found
+			for _, star := range append(stars, tt.star) {
+				if n.Remove(star) == false {
+					t.Errorf("Node.Remove() = false, want the star %v to be found", star)
+				}
+			}
+		})
+	}
+}
+
+func TestNode_Insert_autoExpandEdgeMaxDepth(t *testing.T) {
+	n := NewRootWithConfig(100, TreeConfig{MaxDepth: 2, AutoExpand: true})
+
+	// both stars on the western edge have to be moved into the same quadrant of the grown root, where they can only
+	// be separated below the maximum depth
+	stars := []Star2D{
+		NewStar2D(Vec2{-50, 10}, Vec2{0, 0}, 10),
+		NewStar2D(Vec2{-50, 40}, Vec2{0, 0}, 20),
+		NewStar2D(Vec2{10, -20}, Vec2{0, 0}, 30),
+	}
+	for _, star := range stars {
+		if err := n.Insert(star); err != nil {
+			t.Fatalf("Node.Insert() error = %v", err)
+		}
+	}
+	boundary, totalMass, centerOfMass := n.Boundary, n.TotalMass, n.CenterOfMass
+
+	if err := n.Insert(NewStar2D(Vec2{-60, 10}, Vec2{0, 0}, 40)); errors.Is(err, ErrMaxDepthExceeded) == false {
+		t.Errorf("Node.Insert() error = %v, want %v", err, ErrMaxDepthExceeded)
+	}
+
+	// the tree is left unchanged
+	if n.Boundary != boundary || n.TotalMass != totalMass || n.CenterOfMass != centerOfMass || n.Depth != 0 {
+		t.Errorf("Node = %v %v %v at depth %v, want %v %v %v at depth 0",
+			n.Boundary, n.TotalMass, n.CenterOfMass, n.Depth, boundary, totalMass, centerOfMass)
+	}
+	for _, star := range stars {
+		if n.Remove(star) == false {
+			t.Errorf("Node.Remove() = false, want the star %v to be found", star)
+		}
+	}
+}
+
+func TestNewRootFitting(t *testing.T) {
+	tests := []struct {
+		name  string
+		stars []Star2D
+		want  BoundingBox
+	}{
+		{
+			name:  "No stars",
+			stars: []Star2D{},
+			want:  BoundingBox{Vec2{0, 0}, 1},
+		},
+		{
+			name:  "A single star",
+			stars: []Star2D{NewStar2D(Vec2{10, 20}, Vec2{0, 0}, 10)},
+			want:  BoundingBox{Vec2{10, 20}, 1},
+		},
+		{
+			name: "Multiple stars",
+			stars: []Star2D{
+				NewStar2D(Vec2{10, 20}, Vec2{0, 0}, 10),
+				NewStar2D(Vec2{-10, 60}, Vec2{0, 0}, 10),
+				NewStar2D(Vec2{30, 0}, Vec2{0, 0}, 10),
+			},
+			want: BoundingBox{Vec2{10, 30}, 60},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := NewRootFitting(tt.stars, TreeConfig{})
+			if root.Boundary != tt.want {
+				t.Errorf("NewRootFitting().Boundary = %v, want %v", root.Boundary, tt.want)
+			}
+			for _, star := range tt.stars {
+				if err := root.Insert(star); err != nil {
+					t.Errorf("Node.Insert() error = %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestNode_Insert_concurrent(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  TreeConfig
+		width   float64
+		stars   int
+		workers int
+	}{
+		{
+			name:    "Single stars per leaf",
+			config:  TreeConfig{},
+			width:   1e4,
+			stars:   20000,
+			workers: 16,
+		},
+		{
+			name:    "Buckets in a growing tree",
+			config:  TreeConfig{MaxDepth: 6, LeafCapacity: 4, AutoExpand: true},
+			width:   10,
+			stars:   20000,
+			workers: 16,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stars := randomStars(tt.stars, 1e4, 42)
+			n := NewRootWithConfig(tt.width, tt.config)
+
+			// insert the stars from multiple goroutines at the same time
+			var wg sync.WaitGroup
+			errs := make(chan error, tt.workers)
+			for worker := 0; worker < tt.workers; worker++ {
+				wg.Add(1)
+				go func(worker int) {
+					defer wg.Done()
+					for i := worker; i < len(stars); i += tt.workers {
+						if err := n.Insert(stars[i]); err != nil {
+							errs <- err
+							return
+						}
+					}
+				}(worker)
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				t.Fatalf("Node.Insert() error = %v", err)
+			}
+
+			// every star has to be in the tree exactly once
+			got := map[Star2D]int{}
+			for _, star := range n.GetAllStars() {
+				got[star]++
+			}
+			if len(got) != len(stars) {
+				t.Errorf("len(Node.GetAllStars()) = %v, want %v", len(got), len(stars))
+			}
+			totalMass := 0.0
+			for _, star := range stars {
+				totalMass += star.M
+				if got[star] != 1 {
+					t.Errorf("star %v is %v times in the tree, want 1", star, got[star])
+				}
+			}
+
+			// the moments have to be the same as if the stars were inserted one after another
+			if math.Abs(n.TotalMass-totalMass) > 1e-9*totalMass {
+				t.Errorf("Node.TotalMass = %v, want %v", n.TotalMass, totalMass)
+			}
+			if tt.config.AutoExpand == false {
+				want := NewRootWithConfig(tt.width, tt.config)
+				for _, star := range stars {
+					if err := want.Insert(star); err != nil {
+						t.Fatalf("Node.Insert() error = %v", err)
+					}
+				}
+				if n.GenForestTree(n) != want.GenForestTree(want) {
+					t.Errorf("Node.Insert() built a different tree when inserting concurrently")
+				}
+				if n.TotalMass != want.TotalMass || n.CenterOfMass != want.CenterOfMass {
+					t.Errorf("Node moments = %v %v, want %v %v", n.TotalMass, n.CenterOfMass, want.TotalMass, want.CenterOfMass)
+				}
+			}
+		})
+	}
+}
+
+func TestNode_Insert_config(t *testing.T) {
+	stars := []Star2D{
+		NewStar2D(Vec2{30, 30}, Vec2{0, 0}, 10),
+		NewStar2D(Vec2{31, 31}, Vec2{0, 0}, 10),
+		NewStar2D(Vec2{-30, 30}, Vec2{0, 0}, 10),
+		NewStar2D(Vec2{32, 32}, Vec2{0, 0}, 10),
+	}
+
+	tests := []struct {
+		name      string
+		config    TreeConfig
+		wantTree  string
+		wantDepth int
+		wantErr   error
+	}{
+		{
+			name:      "Single stars without buckets",
+			config:    TreeConfig{},
+			wantTree:  "[[-30 30[][][][]][[[][][][]][[[][][][]][[][][][]][[[][][][]][32 32[][][][]][[[][][][]][[[][][][]][[[][][][]][31 31[][][][]][30 30[][][][]][[][][][]]][[][][][]][[][][][]]][[][][][]][[][][][]]][[][][][]]][[][][][]]][[][][][]][[][][][]]][[][][][]][[][][][]]]",
+			wantDepth: 7,
+		},
+		{
+			name:      "Single stars without buckets and a maximum depth",
+			config:    NewTreeConfig(2, 0),
+			wantTree:  "[[-30 30[][][][]][30 30[][][][]][[][][][]][[][][][]]]",
+			wantDepth: 1,
+			wantErr:   ErrMaxDepthExceeded,
+		},
+		{
+			name:      "Buckets at the maximum depth",
+			config:    NewTreeConfig(2, 1),
+			wantTree:  "[[-30 30[][][][]][[[][][][]][30 30; 31 31; 32 32[][][][]][[][][][]][[][][][]]][[][][][]][[][][][]]]",
+			wantDepth: 2,
+		},
+		{
+			name:      "Leaves holding multiple stars",
+			config:    NewTreeConfig(0, 3),
+			wantTree:  "[[-30 30[][][][]][30 30; 31 31; 32 32[][][][]][[][][][]][[][][][]]]",
+			wantDepth: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := NewRootWithConfig(100, tt.config)
+
+			var err error
+			for _, star := range stars {
+				if insertErr := n.Insert(star); insertErr != nil {
+					err = insertErr
+				}
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Node.Insert() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got := n.GenForestTree(n); got != tt.wantTree {
+				t.Errorf("Node.GenForestTree() = %v, want %v", got, tt.wantTree)
+			}
+
+			// search the deepest leaf
+			depth := 0
+			var walk func(node *Node)
+			walk = func(node *Node) {
+				if node.Depth > depth {
+					depth = node.Depth
+				}
+				for _, subtree := range node.Subtrees {
+					if subtree != nil {
+						walk(subtree)
+					}
+				}
+			}
+			walk(n)
+			if depth != tt.wantDepth {
+				t.Errorf("Node.Depth = %v, want %v", depth, tt.wantDepth)
+			}
+		})
+	}
+}
+
 // The example below creates a new node using the given bounding box
 func ExampleNewNode() {
 	newNode := NewNode(BoundingBox{
@@ -92,7 +500,7 @@ func ExampleNewNode() {
 		Width: 50,
 	})
 	fmt.Printf("%v\n", newNode)
-	// Output: &{{{25 25} 50} {0 0} 0 0 {{0 0} {0 0} 0} [<nil> <nil> <nil> <nil>]}
+	// Output: {{{25 25} 50} {0 0} 0 0 {0 0 false} [] [<nil> <nil> <nil> <nil>]}
 }
 
 func TestNewNode(t *testing.T) {
@@ -129,18 +537,7 @@ func TestNewNode(t *testing.T) {
 				},
 				TotalMass: 0,
 				Depth:     0,
-				Star: Star2D{
-					C: Vec2{
-						X: 0,
-						Y: 0,
-					},
-					V: Vec2{
-						X: 0,
-						Y: 0,
-					},
-					M: 0,
-				},
-				Subtrees: [4]*Node{},
+				Subtrees:  [4]*Node{},
 			},
 		},
 	}
@@ -164,10 +561,10 @@ func ExampleNode_Subdivide() {
 		fmt.Printf("%v\n", root.Subtrees[i])
 	}
 	// Output:
-	// &{{{-25 25} 50} {0 0} 0 0 {{0 0} {0 0} 0} [<nil> <nil> <nil> <nil>]}
-	// &{{{25 25} 50} {0 0} 0 0 {{0 0} {0 0} 0} [<nil> <nil> <nil> <nil>]}
-	// &{{{-25 -25} 50} {0 0} 0 0 {{0 0} {0 0} 0} [<nil> <nil> <nil> <nil>]}
-	// &{{{25 -25} 50} {0 0} 0 0 {{0 0} {0 0} 0} [<nil> <nil> <nil> <nil>]}
+	// {{{-25 25} 50} {0 0} 0 1 {0 0 false} [] [<nil> <nil> <nil> <nil>]}
+	// {{{25 25} 50} {0 0} 0 1 {0 0 false} [] [<nil> <nil> <nil> <nil>]}
+	// {{{-25 -25} 50} {0 0} 0 1 {0 0 false} [] [<nil> <nil> <nil> <nil>]}
+	// {{{25 -25} 50} {0 0} 0 1 {0 0 false} [] [<nil> <nil> <nil> <nil>]}
 }
 
 func TestNode_Subdivide(t *testing.T) {
@@ -176,7 +573,7 @@ func TestNode_Subdivide(t *testing.T) {
 		CenterOfMass Vec2
 		TotalMass    float64
 		Depth        int
-		Star         Star2D
+		Bodies       []Star2D
 		Subtrees     [4]*Node
 	}
 	tests := []struct {
@@ -199,17 +596,6 @@ func TestNode_Subdivide(t *testing.T) {
 				},
 				TotalMass: 0,
 				Depth:     0,
-				Star: Star2D{
-					C: Vec2{
-						X: 0,
-						Y: 0,
-					},
-					V: Vec2{
-						X: 0,
-						Y: 0,
-					},
-					M: 0,
-				},
 				Subtrees: [4]*Node{
 					{
 						Boundary: BoundingBox{
@@ -225,18 +611,7 @@ func TestNode_Subdivide(t *testing.T) {
 						},
 						TotalMass: 0,
 						Depth:     0,
-						Star: Star2D{
-							C: Vec2{
-								X: 0,
-								Y: 0,
-							},
-							V: Vec2{
-								X: 0,
-								Y: 0,
-							},
-							M: 0,
-						},
-						Subtrees: [4]*Node{},
+						Subtrees:  [4]*Node{},
 					},
 					{
 						Boundary: BoundingBox{
@@ -252,18 +627,7 @@ func TestNode_Subdivide(t *testing.T) {
 						},
 						TotalMass: 0,
 						Depth:     0,
-						Star: Star2D{
-							C: Vec2{
-								X: 0,
-								Y: 0,
-							},
-							V: Vec2{
-								X: 0,
-								Y: 0,
-							},
-							M: 0,
-						},
-						Subtrees: [4]*Node{},
+						Subtrees:  [4]*Node{},
 					},
 					{
 						Boundary: BoundingBox{
@@ -279,18 +643,7 @@ func TestNode_Subdivide(t *testing.T) {
 						},
 						TotalMass: 0,
 						Depth:     0,
-						Star: Star2D{
-							C: Vec2{
-								X: 0,
-								Y: 0,
-							},
-							V: Vec2{
-								X: 0,
-								Y: 0,
-							},
-							M: 0,
-						},
-						Subtrees: [4]*Node{},
+						Subtrees:  [4]*Node{},
 					},
 					{
 						Boundary: BoundingBox{
@@ -306,18 +659,7 @@ func TestNode_Subdivide(t *testing.T) {
 						},
 						TotalMass: 0,
 						Depth:     0,
-						Star: Star2D{
-							C: Vec2{
-								X: 0,
-								Y: 0,
-							},
-							V: Vec2{
-								X: 0,
-								Y: 0,
-							},
-							M: 0,
-						},
-						Subtrees: [4]*Node{},
+						Subtrees:  [4]*Node{},
 					},
 				},
 			},
@@ -330,7 +672,7 @@ func TestNode_Subdivide(t *testing.T) {
 				CenterOfMass: tt.fields.CenterOfMass,
 				TotalMass:    tt.fields.TotalMass,
 				Depth:        tt.fields.Depth,
-				Star:         tt.fields.Star,
+				Bodies:       tt.fields.Bodies,
 				Subtrees:     tt.fields.Subtrees,
 			}
 			n.Subdivide()
@@ -367,13 +709,12 @@ func ExampleNode_Insert() {
 	fmt.Printf("%v", root)
 
 	// Output:
-	// Direct insert of (12.000000, 34.000000)
-	// &{{{0 0} 100} {0 0} 0 0 {{12 34} {0 0} 0} [<nil> <nil> <nil> <nil>]}
+	// {{{0 0} 100} {0 0} 0 0 {0 0 false} [{{12 34} {0 0} 0}] [<nil> <nil> <nil> <nil>]}
 }
 
 // Insert two stars that are very close to each other into the tree.
 // A problem arises: the tree has to be subdivided so often, that the insert function
-// raises a recursion depth error
+// returns an error wrapping ErrMaxDepthExceeded
 func ExampleNode_Insert_error() {
 
 	// Initialize a tree and two
@@ -410,65 +751,12 @@ func ExampleNode_Insert_error() {
 
 	// insert the second star into the tree
 	err = root.Insert(star2)
-	// handle potential errors
-	if err != nil {
-		panic(err)
-	}
+	fmt.Println(err)
+	fmt.Println(errors.Is(err, ErrMaxDepthExceeded))
 
 	// Output:
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Direct insert of (5.000000, 5.000000)
-	// Could not insert star (5.000000, 5.000000) (recursion limit reached)
-	//
-	// Could not insert star (5.000000, 5.000000) (recursion limit reached)
+	// could not insert star (5.000000, 5.000000): maximum depth of the tree exceeded
+	// true
 }
 
 func TestNode_Insert(t *testing.T) {
@@ -477,7 +765,7 @@ func TestNode_Insert(t *testing.T) {
 		CenterOfMass Vec2
 		TotalMass    float64
 		Depth        int
-		Star         Star2D
+		Bodies       []Star2D
 		Subtrees     [4]*Node
 	}
 	type args struct {
@@ -487,10 +775,10 @@ func TestNode_Insert(t *testing.T) {
 		name    string
 		fields  fields
 		args    args
-		wantErr bool
+		wantErr error
 	}{
 		{
-			name: "Inserting a single star into a previously empty galaxy",
+			name: "Inserting a star at the origin without mass into a previously empty galaxy",
 			fields: fields{
 				Boundary: BoundingBox{
 					Center: Vec2{
@@ -505,7 +793,10 @@ func TestNode_Insert(t *testing.T) {
 				},
 				TotalMass: 0,
 				Depth:     0,
-				Star: Star2D{
+				Subtrees:  [4]*Node{},
+			},
+			args: args{
+				star: Star2D{
 					C: Vec2{
 						X: 0,
 						Y: 0,
@@ -516,13 +807,43 @@ func TestNode_Insert(t *testing.T) {
 					},
 					M: 0,
 				},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "Inserting a single star into a galaxy all ready containing a star",
+			fields: fields{
+				Boundary: BoundingBox{
+					Center: Vec2{
+						X: 0,
+						Y: 0,
+					},
+					Width: 100,
+				},
+				CenterOfMass: Vec2{
+					X: 0,
+					Y: 0,
+				},
+				TotalMass: 0,
+				Depth:     0,
+				Bodies: []Star2D{{
+					C: Vec2{
+						X: 2,
+						Y: 3,
+					},
+					V: Vec2{
+						X: 0,
+						Y: 0,
+					},
+					M: 0,
+				}},
 				Subtrees: [4]*Node{},
 			},
 			args: args{
 				star: Star2D{
 					C: Vec2{
-						X: 0,
-						Y: 0,
+						X: 10,
+						Y: 20,
 					},
 					V: Vec2{
 						X: 0,
@@ -531,10 +852,10 @@ func TestNode_Insert(t *testing.T) {
 					M: 0,
 				},
 			},
-			wantErr: false,
+			wantErr: nil,
 		},
 		{
-			name: "Inserting a single star into a galaxy all ready containing a star",
+			name: "Inserting a single star onto the boundary limit",
 			fields: fields{
 				Boundary: BoundingBox{
 					Center: Vec2{
@@ -549,36 +870,63 @@ func TestNode_Insert(t *testing.T) {
 				},
 				TotalMass: 0,
 				Depth:     0,
-				Star: Star2D{
+				Bodies: []Star2D{{
 					C: Vec2{
-						X: 2,
-						Y: 3,
+						X: 10,
+						Y: 20,
+					},
+					V: Vec2{
+						X: 0,
+						Y: 0,
+					},
+					M: 0,
+				}},
+				Subtrees: [4]*Node{},
+			},
+			args: args{
+				star: Star2D{
+					C: Vec2{
+						X: 25,
+						Y: 25,
 					},
 					V: Vec2{
 						X: 0,
 						Y: 0,
 					},
-					M: 0,
+					M: 20,
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "Inserting a single star outside of the galaxy",
+			fields: fields{
+				Boundary: BoundingBox{
+					Center: Vec2{
+						X: 0,
+						Y: 0,
+					},
+					Width: 100,
 				},
 				Subtrees: [4]*Node{},
 			},
 			args: args{
 				star: Star2D{
 					C: Vec2{
-						X: 10,
-						Y: 20,
+						X: 60,
+						Y: -20,
 					},
 					V: Vec2{
 						X: 0,
 						Y: 0,
 					},
-					M: 0,
+					M: 20,
 				},
 			},
-			wantErr: false,
+			wantErr: ErrOutOfBounds,
 		},
 		{
-			name: "Inserting a single star onto the boundary limit",
+			name: "Inserting a single star onto the position of another star",
 			fields: fields{
 				Boundary: BoundingBox{
 					Center: Vec2{
@@ -587,13 +935,7 @@ func TestNode_Insert(t *testing.T) {
 					},
 					Width: 100,
 				},
-				CenterOfMass: Vec2{
-					X: 0,
-					Y: 0,
-				},
-				TotalMass: 0,
-				Depth:     0,
-				Star: Star2D{
+				Bodies: []Star2D{{
 					C: Vec2{
 						X: 10,
 						Y: 20,
@@ -603,23 +945,23 @@ func TestNode_Insert(t *testing.T) {
 						Y: 0,
 					},
 					M: 0,
-				},
+				}},
 				Subtrees: [4]*Node{},
 			},
 			args: args{
 				star: Star2D{
 					C: Vec2{
-						X: 25,
-						Y: 25,
+						X: 10,
+						Y: 20,
 					},
 					V: Vec2{
-						X: 0,
-						Y: 0,
+						X: 1,
+						Y: 2,
 					},
 					M: 20,
 				},
 			},
-			wantErr: false,
+			wantErr: ErrDuplicatePosition,
 		},
 	}
 	for _, tt := range tests {
@@ -629,21 +971,299 @@ func TestNode_Insert(t *testing.T) {
 				CenterOfMass: tt.fields.CenterOfMass,
 				TotalMass:    tt.fields.TotalMass,
 				Depth:        tt.fields.Depth,
-				Star:         tt.fields.Star,
+				Bodies:       tt.fields.Bodies,
 				Subtrees:     tt.fields.Subtrees,
 			}
-			if err := n.Insert(tt.args.star); (err != nil) != tt.wantErr {
+			if err := n.Insert(tt.args.star); !errors.Is(err, tt.wantErr) {
 				t.Errorf("Node.Insert() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
 
+// Remove a star from a tree.
+// After removing one of the two stars, the subtrees only hold a single star and get merged back into the root.
+func ExampleNode_Remove() {
+	root := NewRoot(100)
+	star1 := NewStar2D(Vec2{10, 20}, Vec2{0, 0}, 10)
+	star2 := NewStar2D(Vec2{-10, -20}, Vec2{0, 0}, 10)
+
+	// There will be no error handling here, we'll assume that everything goes right..
+	_ = root.Insert(star1)
+	_ = root.Insert(star2)
+
+	// remove the second star from the tree
+	removed := root.Remove(star2)
+
+	fmt.Println(removed)
+	fmt.Println(root.GenForestTree(root))
+	// Output:
+	// true
+	// [10 20[][][][]]
+}
+
+func TestNode_Remove(t *testing.T) {
+	star1 := NewStar2D(Vec2{10, 20}, Vec2{0, 0}, 10)
+	star2 := NewStar2D(Vec2{-10, -20}, Vec2{0, 0}, 20)
+	star3 := NewStar2D(Vec2{12, 22}, Vec2{0, 0}, 30)
+
+	tests := []struct {
+		name         string
+		insert       []Star2D
+		remove       Star2D
+		want         bool
+		wantStars    []Star2D
+		wantMass     float64
+		wantCOM      Vec2
+		wantSubtrees bool
+	}{
+		{
+			name:         "Remove the only star of the tree",
+			insert:       []Star2D{star1},
+			remove:       star1,
+			want:         true,
+			wantStars:    []Star2D{},
+			wantMass:     0,
+			wantCOM:      Vec2{0, 0},
+			wantSubtrees: false,
+		},
+		{
+			name:         "Remove a star and collapse the subtrees",
+			insert:       []Star2D{star1, star2},
+			remove:       star2,
+			want:         true,
+			wantStars:    []Star2D{star1},
+			wantMass:     10,
+			wantCOM:      Vec2{10, 20},
+			wantSubtrees: false,
+		},
+		{
+			name:         "Remove a star and collapse a deep subtree",
+			insert:       []Star2D{star1, star2, star3},
+			remove:       star3,
+			want:         true,
+			wantStars:    []Star2D{star1, star2},
+			wantMass:     30,
+			wantCOM:      Vec2{-10.0 / 3, -20.0 / 3},
+			wantSubtrees: true,
+		},
+		{
+			name:         "Remove a star that is not in the tree",
+			insert:       []Star2D{star1, star2},
+			remove:       star3,
+			want:         false,
+			wantStars:    []Star2D{star1, star2},
+			wantMass:     30,
+			wantCOM:      Vec2{-10.0 / 3, -20.0 / 3},
+			wantSubtrees: true,
+		},
+		{
+			name:         "Remove a star at the origin without mass and velocity",
+			insert:       []Star2D{star1, Star2D{}},
+			remove:       Star2D{},
+			want:         true,
+			wantStars:    []Star2D{star1},
+			wantMass:     10,
+			wantCOM:      Vec2{10, 20},
+			wantSubtrees: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := NewRoot(100)
+			for _, star := range tt.insert {
+				if err := n.Insert(star); err != nil {
+					t.Fatalf("Node.Insert() error = %v", err)
+				}
+			}
+
+			got := n.Remove(tt.remove)
+			if got != tt.want {
+				t.Errorf("Node.Remove() = %v, want %v", got, tt.want)
+			}
+			if stars := n.GetAllStars(); !reflect.DeepEqual(stars, tt.wantStars) {
+				t.Errorf("Node.GetAllStars() = %v, want %v", stars, tt.wantStars)
+			}
+			if math.Abs(n.TotalMass-tt.wantMass) > 1e-12 || math.Abs(n.CenterOfMass.X-tt.wantCOM.X) > 1e-12 ||
+				math.Abs(n.CenterOfMass.Y-tt.wantCOM.Y) > 1e-12 {
+				t.Errorf("Node moments = %v %v, want %v %v", n.TotalMass, n.CenterOfMass, tt.wantMass, tt.wantCOM)
+			}
+			if (n.Subtrees != [4]*Node{}) != tt.wantSubtrees {
+				t.Errorf("Node.Subtrees = %v, want subtrees: %v", n.Subtrees, tt.wantSubtrees)
+			}
+		})
+	}
+}
+
+// Update a star in the tree after it moved.
+// The star moves from the north east into the south west quadrant, so it gets removed and reinserted.
+func ExampleNode_Update() {
+	root := NewRoot(100)
+	star1 := NewStar2D(Vec2{10, 20}, Vec2{0, 0}, 10)
+	star2 := NewStar2D(Vec2{30, 30}, Vec2{0, 0}, 10)
+
+	// There will be no error handling here, we'll assume that everything goes right..
+	_ = root.Insert(star1)
+	_ = root.Insert(star2)
+
+	// move the second star
+	moved := NewStar2D(Vec2{-30, -30}, Vec2{0, 0}, 10)
+	err := root.Update(star2, moved)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(root.GenForestTree(root))
+	fmt.Println(root.TotalMass, root.CenterOfMass)
+	// Output:
+	// [[[][][][]][10 20[][][][]][-30 -30[][][][]][[][][][]]]
+	// 20 {-10 -5}
+}
+
+func TestNode_Update(t *testing.T) {
+	star1 := NewStar2D(Vec2{10, 20}, Vec2{0, 0}, 10)
+	star2 := NewStar2D(Vec2{-10, -20}, Vec2{0, 0}, 20)
+	star3 := NewStar2D(Vec2{12, 22}, Vec2{0, 0}, 30)
+
+	type args struct {
+		oldStar Star2D
+		newStar Star2D
+	}
+	tests := []struct {
+		name    string
+		insert  []Star2D
+		args    args
+		want    []Star2D
+		wantErr bool
+	}{
+		{
+			name:   "Move a star inside of its leaf",
+			insert: []Star2D{star1, star2},
+			args: args{
+				oldStar: star1,
+				newStar: NewStar2D(Vec2{11, 21}, Vec2{1, 1}, 10),
+			},
+			want: []Star2D{NewStar2D(Vec2{11, 21}, Vec2{1, 1}, 10), star2},
+		},
+		{
+			name:   "Move a star into another quadrant of the root",
+			insert: []Star2D{star1, star2, star3},
+			args: args{
+				oldStar: star3,
+				newStar: NewStar2D(Vec2{-12, 22}, Vec2{0, 0}, 30),
+			},
+			want: []Star2D{NewStar2D(Vec2{-12, 22}, Vec2{0, 0}, 30), star1, star2},
+		},
+		{
+			name:   "Move a star into another quadrant of a subtree",
+			insert: []Star2D{star1, star2, star3},
+			args: args{
+				oldStar: star3,
+				newStar: NewStar2D(Vec2{40, 40}, Vec2{0, 0}, 30),
+			},
+			want: []Star2D{star1, NewStar2D(Vec2{40, 40}, Vec2{0, 0}, 30), star2},
+		},
+		{
+			name:   "Move a star out of the tree",
+			insert: []Star2D{star1, star2},
+			args: args{
+				oldStar: star1,
+				newStar: NewStar2D(Vec2{200, 20}, Vec2{0, 0}, 10),
+			},
+			want:    []Star2D{star1, star2},
+			wantErr: true,
+		},
+		{
+			name:   "Move a star that is not in the tree",
+			insert: []Star2D{star1, star2},
+			args: args{
+				oldStar: star3,
+				newStar: NewStar2D(Vec2{-12, 22}, Vec2{0, 0}, 30),
+			},
+			want:    []Star2D{star1, star2},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := NewRoot(100)
+			for _, star := range tt.insert {
+				if err := n.Insert(star); err != nil {
+					t.Fatalf("Node.Insert() error = %v", err)
+				}
+			}
+
+			if err := n.Update(tt.args.oldStar, tt.args.newStar); (err != nil) != tt.wantErr {
+				t.Errorf("Node.Update() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			// the updated tree has to look exactly like a tree built from scratch
+			rebuilt := NewRoot(100)
+			for _, star := range tt.want {
+				if err := rebuilt.Insert(star); err != nil {
+					t.Fatalf("Node.Insert() error = %v", err)
+				}
+			}
+			if !reflect.DeepEqual(n, rebuilt) {
+				t.Errorf("Node.Update() = %v, want %v", n.GenForestTree(n), rebuilt.GenForestTree(rebuilt))
+			}
+		})
+	}
+}
+
+func TestNode_Update_autoExpandNotFound(t *testing.T) {
+	n := NewRootWithConfig(100, TreeConfig{AutoExpand: true})
+	star := NewStar2D(Vec2{10, 10}, Vec2{0, 0}, 10)
+	if err := n.Insert(star); err != nil {
+		t.Fatalf("Node.Insert() error = %v", err)
+	}
+
+	// the star to move isn't in the tree, so the root must not grow towards the new position
+	missing := NewStar2D(Vec2{-10, 10}, Vec2{0, 0}, 10)
+	err := n.Update(missing, NewStar2D(Vec2{300, 300}, Vec2{0, 0}, 10))
+	if errors.Is(err, ErrStarNotFound) == false {
+		t.Errorf("Node.Update() error = %v, want %v", err, ErrStarNotFound)
+	}
+	if want := NewBoundingBox(Vec2{0, 0}, 100); n.Boundary != want {
+		t.Errorf("Node.Boundary = %v, want %v", n.Boundary, want)
+	}
+}
+
+func TestNode_Update_autoExpandEdge(t *testing.T) {
+	n := NewRootWithConfig(100, TreeConfig{AutoExpand: true})
+
+	// the stars are on the edges of the boundary, so they lie on the center lines of the grown boundary
+	stars := []Star2D{
+		NewStar2D(Vec2{-50, 10}, Vec2{0, 0}, 10),
+		NewStar2D(Vec2{10, -50}, Vec2{0, 0}, 20),
+		NewStar2D(Vec2{20, 20}, Vec2{0, 0}, 30),
+	}
+	for _, star := range stars {
+		if err := n.Insert(star); err != nil {
+			t.Fatalf("Node.Insert() error = %v", err)
+		}
+	}
+
+	// moving a star out of the tree grows the root towards the south west
+	moved := NewStar2D(Vec2{-60, -60}, Vec2{0, 0}, 30)
+	if err := n.Update(stars[2], moved); err != nil {
+		t.Fatalf("Node.Update() error = %v", err)
+	}
+	stars[2] = moved
+
+	// every star is stored in the quadrant it belongs to, so it can still be found
+	for _, star := range stars {
+		if n.Remove(star) == false {
+			t.Errorf("Node.Remove() = false, want the star %v to be found", star)
+		}
+	}
+}
+
 // Generate a tree using the LaTeX forest tree notation
 // This is a minimal example using only a root node
 func ExampleNode_GenForestTree() { // Create a new root
 	root := NewRoot(100)
-	root.Star = Star2D{
+	root.Bodies = []Star2D{{
 		C: Vec2{
 			X: 10,
 			Y: 20,
@@ -653,7 +1273,7 @@ func ExampleNode_GenForestTree() { // Create a new root
 			Y: 0,
 		},
 		M: 20,
-	}
+	}}
 
 	// generate the tree
 	forestTree := root.GenForestTree(root)
@@ -673,7 +1293,7 @@ func ExampleNode_GenForestTree_deepTree() {
 	root.Subtrees[1].Subdivide()
 
 	// Insert a star into the tree
-	root.Subtrees[1].Star = Star2D{
+	root.Subtrees[1].Bodies = []Star2D{{
 		C: Vec2{
 			X: 20,
 			Y: 30,
@@ -683,7 +1303,7 @@ func ExampleNode_GenForestTree_deepTree() {
 			Y: 0,
 		},
 		M: 20,
-	}
+	}}
 
 	// Generate the tree
 	forestTree := root.GenForestTree(root)
@@ -698,7 +1318,7 @@ func TestNode_GenForestTree(t *testing.T) {
 		CenterOfMass Vec2
 		TotalMass    float64
 		Depth        int
-		Star         Star2D
+		Bodies       []Star2D
 		Subtrees     [4]*Node
 	}
 	type args struct {
@@ -726,18 +1346,7 @@ func TestNode_GenForestTree(t *testing.T) {
 				},
 				TotalMass: 0,
 				Depth:     0,
-				Star: Star2D{
-					C: Vec2{
-						X: 0,
-						Y: 0,
-					},
-					V: Vec2{
-						X: 0,
-						Y: 0,
-					},
-					M: 0,
-				},
-				Subtrees: [4]*Node{},
+				Subtrees:  [4]*Node{},
 			},
 			args: args{
 				node: &Node{
@@ -754,18 +1363,7 @@ func TestNode_GenForestTree(t *testing.T) {
 					},
 					TotalMass: 0,
 					Depth:     0,
-					Star: Star2D{
-						C: Vec2{
-							X: 0,
-							Y: 0,
-						},
-						V: Vec2{
-							X: 0,
-							Y: 0,
-						},
-						M: 0,
-					},
-					Subtrees: [4]*Node{},
+					Subtrees:  [4]*Node{},
 				},
 			},
 			want: "[[][][][]]",
@@ -778,7 +1376,7 @@ func TestNode_GenForestTree(t *testing.T) {
 				CenterOfMass: tt.fields.CenterOfMass,
 				TotalMass:    tt.fields.TotalMass,
 				Depth:        tt.fields.Depth,
-				Star:         tt.fields.Star,
+				Bodies:       tt.fields.Bodies,
 				Subtrees:     tt.fields.Subtrees,
 			}
 			if got := n.GenForestTree(tt.args.node); got != tt.want {
@@ -805,7 +1403,7 @@ func TestNode_DrawTreeLaTeX(t *testing.T) {
 		CenterOfMass Vec2
 		TotalMass    float64
 		Depth        int
-		Star         Star2D
+		Bodies       []Star2D
 		Subtrees     [4]*Node
 	}
 	type args struct {
@@ -832,18 +1430,7 @@ func TestNode_DrawTreeLaTeX(t *testing.T) {
 				},
 				TotalMass: 0,
 				Depth:     0,
-				Star: Star2D{
-					C: Vec2{
-						X: 0,
-						Y: 0,
-					},
-					V: Vec2{
-						X: 0,
-						Y: 0,
-					},
-					M: 0,
-				},
-				Subtrees: [4]*Node{},
+				Subtrees:  [4]*Node{},
 			},
 			args: args{
 				outpath: "tree.tex",
@@ -857,7 +1444,7 @@ func TestNode_DrawTreeLaTeX(t *testing.T) {
 				CenterOfMass: tt.fields.CenterOfMass,
 				TotalMass:    tt.fields.TotalMass,
 				Depth:        tt.fields.Depth,
-				Star:         tt.fields.Star,
+				Bodies:       tt.fields.Bodies,
 				Subtrees:     tt.fields.Subtrees,
 			}
 			n.DrawTreeLaTeX(tt.args.outpath)
@@ -876,7 +1463,7 @@ func ExampleNode_GetAllStars() {
 	root.Subdivide()
 
 	// Insert two stars into the tree
-	root.Subtrees[1].Star = Star2D{
+	root.Subtrees[1].Bodies = []Star2D{{
 		C: Vec2{
 			X: 10,
 			Y: 20,
@@ -886,8 +1473,8 @@ func ExampleNode_GetAllStars() {
 			Y: 0,
 		},
 		M: 0,
-	}
-	root.Subtrees[3].Star = Star2D{
+	}}
+	root.Subtrees[3].Bodies = []Star2D{{
 		C: Vec2{
 			X: 30,
 			Y: 40,
@@ -897,7 +1484,7 @@ func ExampleNode_GetAllStars() {
 			Y: 0,
 		},
 		M: 0,
-	}
+	}}
 
 	// Get the stars from the tree
 	starsList := root.GetAllStars()
@@ -917,7 +1504,7 @@ func TestNode_GetAllStars(t *testing.T) {
 		CenterOfMass Vec2
 		TotalMass    float64
 		Depth        int
-		Star         Star2D
+		Bodies       []Star2D
 		Subtrees     [4]*Node
 	}
 	tests := []struct {
@@ -941,17 +1528,6 @@ func TestNode_GetAllStars(t *testing.T) {
 				},
 				TotalMass: 0,
 				Depth:     0,
-				Star: Star2D{
-					C: Vec2{
-						X: 0,
-						Y: 0,
-					},
-					V: Vec2{
-						X: 0,
-						Y: 0,
-					},
-					M: 0,
-				},
 				Subtrees: [4]*Node{
 					{
 						Boundary: BoundingBox{
@@ -967,7 +1543,7 @@ func TestNode_GetAllStars(t *testing.T) {
 						},
 						TotalMass: 0,
 						Depth:     0,
-						Star: Star2D{
+						Bodies: []Star2D{{
 							C: Vec2{
 								X: 10,
 								Y: 20,
@@ -977,7 +1553,7 @@ func TestNode_GetAllStars(t *testing.T) {
 								Y: 0,
 							},
 							M: 0,
-						},
+						}},
 						Subtrees: [4]*Node{},
 					},
 					{
@@ -994,7 +1570,7 @@ func TestNode_GetAllStars(t *testing.T) {
 						},
 						TotalMass: 0,
 						Depth:     0,
-						Star: Star2D{
+						Bodies: []Star2D{{
 							C: Vec2{
 								X: 30,
 								Y: 40,
@@ -1004,7 +1580,7 @@ func TestNode_GetAllStars(t *testing.T) {
 								Y: 0,
 							},
 							M: 0,
-						},
+						}},
 						Subtrees: [4]*Node{},
 					},
 					{
@@ -1021,7 +1597,7 @@ func TestNode_GetAllStars(t *testing.T) {
 						},
 						TotalMass: 0,
 						Depth:     0,
-						Star: Star2D{
+						Bodies: []Star2D{{
 							C: Vec2{
 								X: 50,
 								Y: 60,
@@ -1031,7 +1607,7 @@ func TestNode_GetAllStars(t *testing.T) {
 								Y: 0,
 							},
 							M: 0,
-						},
+						}},
 						Subtrees: [4]*Node{},
 					},
 					{
@@ -1048,7 +1624,7 @@ func TestNode_GetAllStars(t *testing.T) {
 						},
 						TotalMass: 0,
 						Depth:     0,
-						Star: Star2D{
+						Bodies: []Star2D{{
 							C: Vec2{
 								X: 70,
 								Y: 80,
@@ -1058,7 +1634,7 @@ func TestNode_GetAllStars(t *testing.T) {
 								Y: 0,
 							},
 							M: 0,
-						},
+						}},
 						Subtrees: [4]*Node{},
 					},
 				},
@@ -1118,7 +1694,7 @@ func TestNode_GetAllStars(t *testing.T) {
 				CenterOfMass: tt.fields.CenterOfMass,
 				TotalMass:    tt.fields.TotalMass,
 				Depth:        tt.fields.Depth,
-				Star:         tt.fields.Star,
+				Bodies:       tt.fields.Bodies,
 				Subtrees:     tt.fields.Subtrees,
 			}
 			if got := n.GetAllStars(); !reflect.DeepEqual(got, tt.want) {
@@ -1148,6 +1724,7 @@ func ExampleNode_CalcCenterOfMass() {
 	centerOfMass := root.CalcCenterOfMass()
 	fmt.Println(centerOfMass)
 	// Output:
+	// {1.5 1.5}
 }
 
 func TestNode_CalcCenterOfMass(t *testing.T) {
@@ -1156,7 +1733,7 @@ func TestNode_CalcCenterOfMass(t *testing.T) {
 		CenterOfMass Vec2
 		TotalMass    float64
 		Depth        int
-		Star         Star2D
+		Bodies       []Star2D
 		Subtrees     [4]*Node
 	}
 	tests := []struct {
@@ -1180,17 +1757,6 @@ func TestNode_CalcCenterOfMass(t *testing.T) {
 				},
 				TotalMass: 0,
 				Depth:     0,
-				Star: Star2D{
-					C: Vec2{
-						X: 0,
-						Y: 0,
-					},
-					V: Vec2{
-						X: 0,
-						Y: 0,
-					},
-					M: 0,
-				},
 				Subtrees: [4]*Node{
 					{
 						Boundary: BoundingBox{
@@ -1206,7 +1772,7 @@ func TestNode_CalcCenterOfMass(t *testing.T) {
 						},
 						TotalMass: 0,
 						Depth:     0,
-						Star: Star2D{
+						Bodies: []Star2D{{
 							C: Vec2{
 								X: 10,
 								Y: 20,
@@ -1216,7 +1782,7 @@ func TestNode_CalcCenterOfMass(t *testing.T) {
 								Y: 0,
 							},
 							M: 0,
-						},
+						}},
 						Subtrees: [4]*Node{},
 					},
 					{
@@ -1233,7 +1799,7 @@ func TestNode_CalcCenterOfMass(t *testing.T) {
 						},
 						TotalMass: 0,
 						Depth:     0,
-						Star: Star2D{
+						Bodies: []Star2D{{
 							C: Vec2{
 								X: 30,
 								Y: 40,
@@ -1243,7 +1809,7 @@ func TestNode_CalcCenterOfMass(t *testing.T) {
 								Y: 0,
 							},
 							M: 0,
-						},
+						}},
 						Subtrees: [4]*Node{},
 					},
 					{
@@ -1260,7 +1826,7 @@ func TestNode_CalcCenterOfMass(t *testing.T) {
 						},
 						TotalMass: 0,
 						Depth:     0,
-						Star: Star2D{
+						Bodies: []Star2D{{
 							C: Vec2{
 								X: 50,
 								Y: 60,
@@ -1270,7 +1836,7 @@ func TestNode_CalcCenterOfMass(t *testing.T) {
 								Y: 0,
 							},
 							M: 0,
-						},
+						}},
 						Subtrees: [4]*Node{},
 					},
 					{
@@ -1287,7 +1853,7 @@ func TestNode_CalcCenterOfMass(t *testing.T) {
 						},
 						TotalMass: 0,
 						Depth:     0,
-						Star: Star2D{
+						Bodies: []Star2D{{
 							C: Vec2{
 								X: 70,
 								Y: 80,
@@ -1297,7 +1863,7 @@ func TestNode_CalcCenterOfMass(t *testing.T) {
 								Y: 0,
 							},
 							M: 0,
-						},
+						}},
 						Subtrees: [4]*Node{},
 					},
 				},
@@ -1315,7 +1881,7 @@ func TestNode_CalcCenterOfMass(t *testing.T) {
 				CenterOfMass: tt.fields.CenterOfMass,
 				TotalMass:    tt.fields.TotalMass,
 				Depth:        tt.fields.Depth,
-				Star:         tt.fields.Star,
+				Bodies:       tt.fields.Bodies,
 				Subtrees:     tt.fields.Subtrees,
 			}
 			if got := n.CalcCenterOfMass(); !reflect.DeepEqual(got, tt.want) {
@@ -1336,7 +1902,7 @@ func TestNode_CalcTotalMass(t *testing.T) {
 		CenterOfMass Vec2
 		TotalMass    float64
 		Depth        int
-		Star         Star2D
+		Bodies       []Star2D
 		Subtrees     [4]*Node
 	}
 	tests := []struct {
@@ -1360,17 +1926,6 @@ func TestNode_CalcTotalMass(t *testing.T) {
 				},
 				TotalMass: 0,
 				Depth:     0,
-				Star: Star2D{
-					C: Vec2{
-						X: 0,
-						Y: 0,
-					},
-					V: Vec2{
-						X: 0,
-						Y: 0,
-					},
-					M: 0,
-				},
 				Subtrees: [4]*Node{
 					{
 						Boundary: BoundingBox{
@@ -1386,7 +1941,7 @@ func TestNode_CalcTotalMass(t *testing.T) {
 						},
 						TotalMass: 0,
 						Depth:     0,
-						Star: Star2D{
+						Bodies: []Star2D{{
 							C: Vec2{
 								X: 10,
 								Y: 20,
@@ -1396,7 +1951,7 @@ func TestNode_CalcTotalMass(t *testing.T) {
 								Y: 0,
 							},
 							M: 42,
-						},
+						}},
 						Subtrees: [4]*Node{},
 					},
 					{
@@ -1413,18 +1968,7 @@ func TestNode_CalcTotalMass(t *testing.T) {
 						},
 						TotalMass: 0,
 						Depth:     0,
-						Star: Star2D{
-							C: Vec2{
-								X: 0,
-								Y: 0,
-							},
-							V: Vec2{
-								X: 0,
-								Y: 0,
-							},
-							M: 0,
-						},
-						Subtrees: [4]*Node{},
+						Subtrees:  [4]*Node{},
 					},
 					{
 						Boundary: BoundingBox{
@@ -1440,7 +1984,7 @@ func TestNode_CalcTotalMass(t *testing.T) {
 						},
 						TotalMass: 0,
 						Depth:     0,
-						Star: Star2D{
+						Bodies: []Star2D{{
 							C: Vec2{
 								X: 30,
 								Y: 40,
@@ -1450,7 +1994,7 @@ func TestNode_CalcTotalMass(t *testing.T) {
 								Y: 0,
 							},
 							M: 24,
-						},
+						}},
 						Subtrees: [4]*Node{},
 					},
 					{
@@ -1467,18 +2011,7 @@ func TestNode_CalcTotalMass(t *testing.T) {
 						},
 						TotalMass: 0,
 						Depth:     0,
-						Star: Star2D{
-							C: Vec2{
-								X: 0,
-								Y: 0,
-							},
-							V: Vec2{
-								X: 0,
-								Y: 0,
-							},
-							M: 0,
-						},
-						Subtrees: [4]*Node{},
+						Subtrees:  [4]*Node{},
 					},
 				},
 			},
@@ -1492,7 +2025,7 @@ func TestNode_CalcTotalMass(t *testing.T) {
 				CenterOfMass: tt.fields.CenterOfMass,
 				TotalMass:    tt.fields.TotalMass,
 				Depth:        tt.fields.Depth,
-				Star:         tt.fields.Star,
+				Bodies:       tt.fields.Bodies,
 				Subtrees:     tt.fields.Subtrees,
 			}
 			if got := n.CalcTotalMass(); got != tt.want {
@@ -1513,7 +2046,7 @@ func TestNode_CalcAllForces(t *testing.T) {
 		CenterOfMass Vec2
 		TotalMass    float64
 		Depth        int
-		Star         Star2D
+		Bodies       []Star2D
 		Subtrees     [4]*Node
 	}
 	type args struct {
@@ -1542,18 +2075,7 @@ func TestNode_CalcAllForces(t *testing.T) {
 				},
 				TotalMass: 0,
 				Depth:     0,
-				Star: Star2D{
-					C: Vec2{
-						X: 0,
-						Y: 0,
-					},
-					V: Vec2{
-						X: 0,
-						Y: 0,
-					},
-					M: 0,
-				},
-				Subtrees: [4]*Node{},
+				Subtrees:  [4]*Node{},
 			},
 			args: args{
 				star: Star2D{
@@ -1582,7 +2104,7 @@ func TestNode_CalcAllForces(t *testing.T) {
 				CenterOfMass: tt.fields.CenterOfMass,
 				TotalMass:    tt.fields.TotalMass,
 				Depth:        tt.fields.Depth,
-				Star:         tt.fields.Star,
+				Bodies:       tt.fields.Bodies,
 				Subtrees:     tt.fields.Subtrees,
 			}
 			if got := n.CalcAllForces(tt.args.star, tt.args.theta); !reflect.DeepEqual(got, tt.want) {
@@ -1592,6 +2114,54 @@ func TestNode_CalcAllForces(t *testing.T) {
 	}
 }
 
+func TestNode_CalcAllForces_quadrupole(t *testing.T) {
+	root := NewRoot(20)
+	for _, star := range randomStars(200, 20, 10) {
+		if err := root.Insert(star); err != nil {
+			t.Fatalf("Node.Insert() error = %v", err)
+		}
+	}
+	stars := root.GetAllStars()
+
+	tests := []struct {
+		name string
+		star Star2D
+	}{
+		{
+			name: "star on the x axis",
+			star: NewStar2D(Vec2{60, 0}, Vec2{0, 0}, 1),
+		},
+		{
+			name: "star on the diagonal",
+			star: NewStar2D(Vec2{-45, -45}, Vec2{0, 0}, 1),
+		},
+		{
+			name: "star far away",
+			star: NewStar2D(Vec2{100, 300}, Vec2{0, 0}, 1),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := Vec2{}
+			for _, star := range stars {
+				want = want.Add(CalcForce(tt.star, star))
+			}
+
+			// the whole tree is accepted as a single cell
+			monopole := root.CalcAllForces(tt.star, 0.5)
+			quadrupole := root.CalcAllForces(tt.star, 0.5, WithQuadrupole())
+
+			forceError := func(force Vec2) float64 {
+				return math.Hypot(force.X-want.X, force.Y-want.Y) / math.Hypot(want.X, want.Y)
+			}
+			if forceError(quadrupole) > forceError(monopole)/10 {
+				t.Errorf("Node.CalcAllForces() error with quadrupole = %v, without = %v",
+					forceError(quadrupole), forceError(monopole))
+			}
+		})
+	}
+}
+
 //
 func ExampleCalcForce() {
 
@@ -0,0 +1,188 @@
+// scenario.go builds galaxy collisions and mergers out of generated galaxies
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrInvalidScenario is returned when the galaxies of a scenario can't be placed on the given orbits
+var ErrInvalidScenario = errors.New("invalid scenario")
+
+// Orbit defines the Keplerian orbit of a galaxy around the primary galaxy of a scenario
+type Orbit struct {
+	Pericentre   float64 // Closest distance of the galaxy centers
+	Eccentricity float64 // Eccentricity of the orbit, bound orbits have an eccentricity smaller than 1
+	Separation   float64 // Distance of the galaxy centers the scenario starts at, the pericentre is used if it is zero
+	Angle        float64 // Direction of the pericentre, measured counterclockwise from the x axis
+	Clockwise    bool    // The galaxy orbits the primary galaxy clockwise instead of counterclockwise
+}
+
+// ScenarioGalaxy is a galaxy taking part in a scenario
+type ScenarioGalaxy struct {
+	Stars []Star2D // Stars of the galaxy, for example generated using GenerateGalaxy
+	Orbit Orbit    // Orbit around the primary galaxy, it isn't used for the primary galaxy itself
+
+	// Inclination is the angle between the disk and the orbital plane. The disk is tilted around its line of
+	// nodes and projected onto the plane, so the positions and velocities perpendicular to the line of nodes are
+	// scaled by cos(Inclination). An inclination of pi turns the disk upside down: it spins retrograde.
+	Inclination float64
+
+	// Orientation is the direction of the line of nodes, measured counterclockwise from the x axis
+	Orientation float64
+}
+
+// BuildScenario places the given galaxies on their orbits. The first galaxy is the primary galaxy, every other
+// galaxy starts on its orbit around the primary galaxy approaching its pericentre. The orbits are calculated as
+// two body orbits of the total masses of the galaxies, so the other galaxies don't influence each other's orbit.
+// All the stars are shifted into the centre-of-mass frame of the scenario and tagged using the index of their
+// galaxy in the given slice.
+// The returned width is the width of a root node (NewRoot) fitting the whole encounter: the galaxies on bound
+// orbits until their apocentre and the galaxies on unbound orbits until they are back at their initial separation.
+func BuildScenario(galaxies []ScenarioGalaxy, units Units) ([]Stargalaxy, float64, error) {
+	if len(galaxies) < 2 {
+		return nil, 0, ErrInvalidScenario
+	}
+	G := units.G()
+
+	// center every galaxy and determine its mass and extent
+	centered := make([][]Star2D, len(galaxies))
+	masses := make([]float64, len(galaxies))
+	extent := 0.0
+	for i, galaxy := range galaxies {
+		stars, mass := centerGalaxy(galaxy.Stars)
+		if mass <= 0 || math.IsInf(mass, 0) || math.IsNaN(mass) {
+			return nil, 0, ErrInvalidScenario
+		}
+
+		for j := range stars {
+			stars[j].C = inclineVector(stars[j].C, galaxy.Inclination, galaxy.Orientation)
+			stars[j].V = inclineVector(stars[j].V, galaxy.Inclination, galaxy.Orientation)
+			extent = math.Max(extent, stars[j].C.Len())
+		}
+
+		centered[i], masses[i] = stars, mass
+	}
+
+	// place the galaxies on their orbits relative to the primary galaxy
+	positions := make([]Vec2, len(galaxies))
+	velocities := make([]Vec2, len(galaxies))
+	totalMass := masses[0]
+	maxSeparation := 0.0
+	for i := 1; i < len(galaxies); i++ {
+		position, velocity, separation, err := galaxies[i].Orbit.state(G * (masses[0] + masses[i]))
+		if err != nil {
+			return nil, 0, err
+		}
+		positions[i], velocities[i] = position, velocity
+		totalMass += masses[i]
+		maxSeparation = math.Max(maxSeparation, separation)
+	}
+
+	// shift everything into the centre-of-mass frame
+	var centerOfMass, centerOfMassVelocity Vec2
+	for i := range galaxies {
+		centerOfMass.X += masses[i] * positions[i].X / totalMass
+		centerOfMass.Y += masses[i] * positions[i].Y / totalMass
+		centerOfMassVelocity.X += masses[i] * velocities[i].X / totalMass
+		centerOfMassVelocity.Y += masses[i] * velocities[i].Y / totalMass
+	}
+
+	var scenario []Stargalaxy
+	halfWidth := maxSeparation + extent
+	for i, stars := range centered {
+		for _, star := range stars {
+			star.C = star.C.Add(positions[i].Sub(centerOfMass))
+			star.V = star.V.Add(velocities[i].Sub(centerOfMassVelocity))
+			halfWidth = math.Max(halfWidth, math.Max(math.Abs(star.C.X), math.Abs(star.C.Y)))
+
+			scenario = append(scenario, Stargalaxy{Star: star, Index: int64(i)})
+		}
+	}
+
+	return scenario, 2 * halfWidth, nil
+}
+
+// state returns the position and the velocity of the galaxy relative to the primary galaxy at the start of the
+// scenario and the largest separation of the galaxies during the encounter. mu is G times the mass of both galaxies.
+func (o Orbit) state(mu float64) (Vec2, Vec2, float64, error) {
+	separation := o.Separation
+	if separation == 0 {
+		separation = o.Pericentre
+	}
+	if o.Pericentre <= 0 || o.Eccentricity < 0 || separation < o.Pericentre || mu <= 0 {
+		return Vec2{}, Vec2{}, 0, ErrInvalidScenario
+	}
+
+	maxSeparation := separation
+	if o.Eccentricity < 1 {
+		maxSeparation = o.Pericentre * (1 + o.Eccentricity) / (1 - o.Eccentricity)
+		if separation > maxSeparation*(1+1e-12) {
+			return Vec2{}, Vec2{}, 0, ErrInvalidScenario
+		}
+	}
+
+	// the true anomaly at the separation, it is negative as the galaxy approaches its pericentre
+	semiLatusRectum := o.Pericentre * (1 + o.Eccentricity)
+	anomaly := 0.0
+	if o.Eccentricity > 0 {
+		anomaly = -math.Acos(math.Max(-1, math.Min(1, (semiLatusRectum/separation-1)/o.Eccentricity)))
+	}
+
+	// the state in the frame of the orbit, the x axis points to the pericentre
+	speed := math.Sqrt(mu / semiLatusRectum)
+	position := NewVec2Polar(separation, anomaly)
+	velocity := Vec2{-speed * math.Sin(anomaly), speed * (o.Eccentricity + math.Cos(anomaly))}
+	if o.Clockwise == true {
+		position.Y, velocity.Y = -position.Y, -velocity.Y
+	}
+
+	return position.Rotate(o.Angle), velocity.Rotate(o.Angle), maxSeparation, nil
+}
+
+// centerGalaxy returns a copy of the given stars shifted into their centre-of-mass frame and their total mass
+func centerGalaxy(stars []Star2D) ([]Star2D, float64) {
+	mass := 0.0
+	var position, velocity Vec2
+	for _, star := range stars {
+		mass += star.M
+		position.X += star.M * star.C.X
+		position.Y += star.M * star.C.Y
+		velocity.X += star.M * star.V.X
+		velocity.Y += star.M * star.V.Y
+	}
+	if mass <= 0 {
+		return nil, mass
+	}
+
+	centered := make([]Star2D, len(stars))
+	for i, star := range stars {
+		star.C = star.C.Sub(position.Multiply(1 / mass))
+		star.V = star.V.Sub(velocity.Multiply(1 / mass))
+		centered[i] = star
+	}
+	return centered, mass
+}
+
+// inclineVector tilts the given vector around the line of nodes pointing in the direction orientation and
+// projects it back onto the plane
+func inclineVector(v Vec2, inclination float64, orientation float64) Vec2 {
+	v = v.Rotate(-orientation)
+	v.Y *= math.Cos(inclination)
+	return v.Rotate(orientation)
+}
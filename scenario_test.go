@@ -0,0 +1,296 @@
+// scenario_test.go provides tests for scenario.go
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// BuildScenario places two galaxies on a parabolic orbit
+func ExampleBuildScenario() {
+	primary, _ := GenerateGalaxy(ExponentialDiskModel{Mass: 1, ScaleLength: 1}, GeneratorConfig{Stars: 1000, Seed: 1, Units: NBodyUnits})
+	secondary, _ := GenerateGalaxy(ExponentialDiskModel{Mass: 0.5, ScaleLength: 0.7}, GeneratorConfig{Stars: 500, Seed: 2, Units: NBodyUnits})
+
+	stars, width, err := BuildScenario([]ScenarioGalaxy{
+		{Stars: primary},
+		{Stars: secondary, Orbit: Orbit{Pericentre: 5, Eccentricity: 1, Separation: 40}, Inclination: math.Pi},
+	}, NBodyUnits)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	root := NewRoot(width)
+	for _, star := range stars {
+		root.Insert(star.Star)
+	}
+	fmt.Printf("%v stars in a box of width %.1f\n", len(stars), width)
+	// Output:
+	// 1500 stars in a box of width 98.2
+}
+
+// galaxyState returns the center of mass, the center-of-mass velocity and the mass of the stars of a galaxy
+func galaxyState(stars []Stargalaxy, index int64) (Vec2, Vec2, float64) {
+	var position, velocity Vec2
+	mass := 0.0
+	for _, star := range stars {
+		if star.Index != index {
+			continue
+		}
+		mass += star.Star.M
+		position.X += star.Star.M * star.Star.C.X
+		position.Y += star.Star.M * star.Star.C.Y
+		velocity.X += star.Star.M * star.Star.V.X
+		velocity.Y += star.Star.M * star.Star.V.Y
+	}
+	return Vec2{position.X / mass, position.Y / mass}, Vec2{velocity.X / mass, velocity.Y / mass}, mass
+}
+
+func TestBuildScenario(t *testing.T) {
+	primary, _ := GenerateGalaxy(PlummerModel{Mass: 1, ScaleLength: 1}, GeneratorConfig{Stars: 400, Seed: 27, Units: NBodyUnits})
+	secondary, _ := GenerateGalaxy(HernquistModel{Mass: 0.3, ScaleLength: 0.5}, GeneratorConfig{Stars: 200, Seed: 28, Units: NBodyUnits})
+	tertiary, _ := GenerateGalaxy(KuzminDiskModel{Mass: 0.1, ScaleLength: 0.5}, GeneratorConfig{Stars: 100, Seed: 29, Units: NBodyUnits})
+
+	tests := []struct {
+		name   string
+		orbits []Orbit
+	}{
+		{
+			name:   "parabolic",
+			orbits: []Orbit{{Pericentre: 5, Eccentricity: 1, Separation: 30}},
+		},
+		{
+			name:   "bound at the apocentre",
+			orbits: []Orbit{{Pericentre: 4, Eccentricity: 0.5, Separation: 12, Angle: 1}},
+		},
+		{
+			name:   "hyperbolic and clockwise",
+			orbits: []Orbit{{Pericentre: 3, Eccentricity: 1.5, Separation: 20, Angle: -2, Clockwise: true}},
+		},
+		{
+			name:   "circular",
+			orbits: []Orbit{{Pericentre: 10}},
+		},
+		{
+			name: "three galaxies",
+			orbits: []Orbit{
+				{Pericentre: 5, Eccentricity: 0.8, Separation: 20},
+				{Pericentre: 8, Eccentricity: 1.2, Separation: 25, Angle: 3, Clockwise: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			galaxies := []ScenarioGalaxy{{Stars: primary}, {Stars: secondary, Orbit: tt.orbits[0]}}
+			if len(tt.orbits) > 1 {
+				galaxies = append(galaxies, ScenarioGalaxy{Stars: tertiary, Orbit: tt.orbits[1]})
+			}
+
+			stars, width, err := BuildScenario(galaxies, NBodyUnits)
+			if err != nil {
+				t.Fatalf("BuildScenario() error = %v", err)
+			}
+
+			// the stars are tagged using the index of their galaxy
+			for i, galaxy := range galaxies {
+				count := 0
+				for _, star := range stars {
+					if star.Index == int64(i) {
+						count++
+					}
+				}
+				if count != len(galaxy.Stars) {
+					t.Errorf("BuildScenario() galaxy %v has %v stars, want %v", i, count, len(galaxy.Stars))
+				}
+			}
+
+			// the scenario is in its centre-of-mass frame
+			var momentum, moment Vec2
+			for _, star := range stars {
+				moment.X += star.Star.M * star.Star.C.X
+				moment.Y += star.Star.M * star.Star.C.Y
+				momentum.X += star.Star.M * star.Star.V.X
+				momentum.Y += star.Star.M * star.Star.V.Y
+			}
+			if math.Hypot(moment.X, moment.Y) > 1e-12 || math.Hypot(momentum.X, momentum.Y) > 1e-12 {
+				t.Errorf("BuildScenario() center of mass = %v, momentum = %v, want zero", moment, momentum)
+			}
+
+			// the galaxies are on the given orbits around the primary galaxy
+			primaryPosition, primaryVelocity, primaryMass := galaxyState(stars, 0)
+			for i, orbit := range tt.orbits {
+				position, velocity, mass := galaxyState(stars, int64(i+1))
+				r := Vec2{position.X - primaryPosition.X, position.Y - primaryPosition.Y}
+				v := Vec2{velocity.X - primaryVelocity.X, velocity.Y - primaryVelocity.Y}
+				mu := NBodyUnits.G() * (primaryMass + mass)
+
+				separation := math.Hypot(r.X, r.Y)
+				angularMomentum := r.X*v.Y - r.Y*v.X
+				energy := (v.X*v.X+v.Y*v.Y)/2 - mu/separation
+				eccentricity := math.Sqrt(math.Max(0, 1+2*energy*angularMomentum*angularMomentum/(mu*mu)))
+				pericentre := angularMomentum * angularMomentum / (mu * (1 + eccentricity))
+
+				wantSeparation := orbit.Separation
+				if wantSeparation == 0 {
+					wantSeparation = orbit.Pericentre
+				}
+				if math.Abs(separation-wantSeparation) > 1e-9*wantSeparation {
+					t.Errorf("BuildScenario() separation = %v, want %v", separation, wantSeparation)
+				}
+				if math.Abs(eccentricity-orbit.Eccentricity) > 1e-6 || math.Abs(pericentre-orbit.Pericentre) > 1e-9*orbit.Pericentre {
+					t.Errorf("BuildScenario() orbit = (%v, %v), want (%v, %v)", pericentre, eccentricity, orbit.Pericentre, orbit.Eccentricity)
+				}
+				if (angularMomentum < 0) != orbit.Clockwise {
+					t.Errorf("BuildScenario() angular momentum = %v, want clockwise %v", angularMomentum, orbit.Clockwise)
+				}
+				if r.X*v.X+r.Y*v.Y > 1e-9*separation*math.Hypot(v.X, v.Y) {
+					t.Errorf("BuildScenario() radial velocity = %v, want the galaxies approaching", r.X*v.X+r.Y*v.Y)
+				}
+			}
+
+			// the root node fits the encounter
+			root := NewRoot(width)
+			for _, star := range stars {
+				if star.Star.InsideOf(root.Boundary) == false {
+					t.Errorf("BuildScenario() star %v outside of the root of width %v", star.Star.C, width)
+				}
+			}
+			for _, orbit := range tt.orbits {
+				if orbit.Eccentricity < 1 && width < 2*orbit.Pericentre*(1+orbit.Eccentricity)/(1-orbit.Eccentricity) {
+					t.Errorf("BuildScenario() width = %v, want the apocentre to fit", width)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildScenario_inclination(t *testing.T) {
+	disk, _ := GenerateGalaxy(ExponentialDiskModel{Mass: 1, ScaleLength: 1}, GeneratorConfig{Stars: 300, Seed: 30, Units: NBodyUnits})
+	orbit := Orbit{Pericentre: 5, Eccentricity: 1, Separation: 20}
+
+	// spin returns the angular momentum and the moment of inertia of a galaxy around its center
+	spin := func(stars []Stargalaxy, index int64) (float64, float64) {
+		center, velocity, _ := galaxyState(stars, index)
+		var angularMomentum, inertia float64
+		for _, star := range stars {
+			if star.Index != index {
+				continue
+			}
+			r := Vec2{star.Star.C.X - center.X, star.Star.C.Y - center.Y}
+			v := Vec2{star.Star.V.X - velocity.X, star.Star.V.Y - velocity.Y}
+			angularMomentum += star.Star.M * (r.X*v.Y - r.Y*v.X)
+			inertia += star.Star.M * (r.X*r.X + r.Y*r.Y)
+		}
+		return angularMomentum, inertia
+	}
+
+	tests := []struct {
+		name            string
+		inclination     float64
+		orientation     float64
+		angularMomentum float64 // angular momentum relative to the prograde disk
+		minInertia      float64 // moment of inertia relative to the prograde disk
+		maxInertia      float64
+	}{
+		{
+			name:            "retrograde",
+			inclination:     math.Pi,
+			angularMomentum: -1,
+			minInertia:      1,
+			maxInertia:      1,
+		},
+		{
+			name:            "inclined",
+			inclination:     math.Pi / 3,
+			orientation:     0.7,
+			angularMomentum: 0.5,
+			minInertia:      0.25,
+			maxInertia:      1,
+		},
+		{
+			name:            "edge-on",
+			inclination:     math.Pi / 2,
+			orientation:     -1.2,
+			angularMomentum: 0,
+			minInertia:      0,
+			maxInertia:      1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stars, _, err := BuildScenario([]ScenarioGalaxy{
+				{Stars: disk},
+				{Stars: disk, Orbit: orbit, Inclination: tt.inclination, Orientation: tt.orientation},
+			}, NBodyUnits)
+			if err != nil {
+				t.Fatalf("BuildScenario() error = %v", err)
+			}
+
+			wantAngularMomentum, wantInertia := spin(stars, 0)
+			angularMomentum, inertia := spin(stars, 1)
+			if math.Abs(angularMomentum-tt.angularMomentum*wantAngularMomentum) > 1e-9*math.Abs(wantAngularMomentum) {
+				t.Errorf("BuildScenario() angular momentum = %v, want %v", angularMomentum, tt.angularMomentum*wantAngularMomentum)
+			}
+			if inertia < tt.minInertia*wantInertia*(1-1e-9) || inertia > tt.maxInertia*wantInertia*(1+1e-9) {
+				t.Errorf("BuildScenario() moment of inertia = %v, want between %v and %v", inertia, tt.minInertia*wantInertia, tt.maxInertia*wantInertia)
+			}
+		})
+	}
+}
+
+func TestBuildScenario_error(t *testing.T) {
+	galaxy := []Star2D{NewStar2D(Vec2{}, Vec2{}, 1)}
+	tests := []struct {
+		name     string
+		galaxies []ScenarioGalaxy
+	}{
+		{
+			name:     "single galaxy",
+			galaxies: []ScenarioGalaxy{{Stars: galaxy}},
+		},
+		{
+			name:     "galaxy without stars",
+			galaxies: []ScenarioGalaxy{{Stars: galaxy}, {Orbit: Orbit{Pericentre: 1}}},
+		},
+		{
+			name:     "galaxy without a pericentre",
+			galaxies: []ScenarioGalaxy{{Stars: galaxy}, {Stars: galaxy, Orbit: Orbit{Eccentricity: 1, Separation: 10}}},
+		},
+		{
+			name:     "negative eccentricity",
+			galaxies: []ScenarioGalaxy{{Stars: galaxy}, {Stars: galaxy, Orbit: Orbit{Pericentre: 1, Eccentricity: -0.5}}},
+		},
+		{
+			name:     "separation inside of the pericentre",
+			galaxies: []ScenarioGalaxy{{Stars: galaxy}, {Stars: galaxy, Orbit: Orbit{Pericentre: 5, Eccentricity: 1, Separation: 2}}},
+		},
+		{
+			name:     "separation outside of the apocentre",
+			galaxies: []ScenarioGalaxy{{Stars: galaxy}, {Stars: galaxy, Orbit: Orbit{Pericentre: 5, Eccentricity: 0.5, Separation: 20}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := BuildScenario(tt.galaxies, NBodyUnits); errors.Is(err, ErrInvalidScenario) == false {
+				t.Errorf("BuildScenario() error = %v, want %v", err, ErrInvalidScenario)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+// softening.go defines the kernels used to soften the gravitational force of close encounters
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+import "math"
+
+// SofteningKernel defines how the force in between two stars is softened when they get close to each other.
+// Softening keeps the forces of close encounters finite and lets coincident stars exert no force at all instead
+// of resulting in NaN or Inf.
+type SofteningKernel int
+
+const (
+	// NoSoftening uses the plain Newtonian force G*m1*m2/r^2
+	NoSoftening SofteningKernel = iota
+
+	// PlummerSoftening replaces the force by G*m1*m2*r/(r^2+eps^2)^(3/2), the force exerted by a Plummer sphere of
+	// the scale length eps. The force is never exactly Newtonian, at r = eps it is only 2^(-3/2), about 35%, of the
+	// Newtonian force and at r = 10*eps it is still 1.5% smaller. The force reaches its maximum at r = eps/sqrt(2)
+	// and vanishes at r = 0.
+	PlummerSoftening
+
+	// SplineSoftening uses the cubic spline kernel used by Gadget. The mass of a star is distributed over a sphere
+	// of the radius h = 2.8*eps, so the force is exactly Newtonian for r >= h. At r = 0 the potential equals the
+	// potential of a Plummer sphere of the scale length eps and the force vanishes.
+	SplineSoftening
+)
+
+// splineRadiusFactor is the ratio in between the radius of the spline kernel and the softening length
+const splineRadiusFactor = 2.8
+
+// WithSoftening softens the forces in between the stars using the given kernel and softening length eps.
+// The forces exerted by cells accepted by the opening criterion are softened the same way, their quadrupole
+// correction is not softened.
+func WithSoftening(kernel SofteningKernel, length float64) ForceOption {
+	return func(options *forceOptions) {
+		options.kernel = kernel
+		options.softeningLength = length
+		options.adaptiveSoftening = nil
+		options.adaptiveSoftening3D = nil
+	}
+}
+
+// WithAdaptiveSoftening softens the forces in between the stars using the given kernel and a softening length
+// defined for every single star. Two stars are softened using the larger of their softening lengths, so the forces
+// stay symmetric. Cells accepted by the opening criterion are softened using the softening length of the star the
+// force acts on. Node3D ignores this option, use WithAdaptiveSoftening3D there.
+func WithAdaptiveSoftening(kernel SofteningKernel, length func(star Star2D) float64) ForceOption {
+	return func(options *forceOptions) {
+		options.kernel = kernel
+		options.adaptiveSoftening = length
+	}
+}
+
+// WithAdaptiveSoftening3D softens the forces in between the stars of a Node3D the same way WithAdaptiveSoftening
+// softens the forces in between the stars of a Node. Node ignores this option.
+func WithAdaptiveSoftening3D(kernel SofteningKernel, length func(star Star3D) float64) ForceOption {
+	return func(options *forceOptions) {
+		options.kernel = kernel
+		options.adaptiveSoftening3D = length
+	}
+}
+
+// softening returns the softening length used in between the given stars
+func (options *forceOptions) softening(stars ...Star2D) float64 {
+	if options.adaptiveSoftening == nil {
+		return options.softeningLength
+	}
+
+	length := 0.0
+	for _, star := range stars {
+		length = math.Max(length, options.adaptiveSoftening(star))
+	}
+	return length
+}
+
+// softening3D returns the softening length used in between the given stars of a Node3D
+func (options *forceOptions) softening3D(stars ...Star3D) float64 {
+	if options.adaptiveSoftening3D == nil {
+		return options.softeningLength
+	}
+
+	length := 0.0
+	for _, star := range stars {
+		length = math.Max(length, options.adaptiveSoftening3D(star))
+	}
+	return length
+}
+
+// softenedForceFactor returns the factor f(r) the vector in between two stars has to be multiplied with to get the
+// force G*m1*m2*f(r)*r. Without softening, f(r) is 1/r^3.
+func softenedForceFactor(kernel SofteningKernel, length float64, distanceSquared float64) float64 {
+	switch kernel {
+	case PlummerSoftening:
+		var softenedSquared float64 = distanceSquared + length*length
+		return 1 / (softenedSquared * math.Sqrt(softenedSquared))
+
+	case SplineSoftening:
+		var h float64 = splineRadiusFactor * length
+		var distance float64 = math.Sqrt(distanceSquared)
+		if distance >= h {
+			return 1 / (distanceSquared * distance)
+		}
+
+		var u float64 = distance / h
+		if u < 0.5 {
+			return (10.666666666667 + u*u*(32*u-38.4)) / (h * h * h)
+		}
+		return (21.333333333333 - 48*u + 38.4*u*u - 10.666666666667*u*u*u - 0.066666666667/(u*u*u)) / (h * h * h)
+
+	default:
+		var distance float64 = math.Sqrt(distanceSquared)
+		return 1 / (distanceSquared * distance)
+	}
+}
+
+// softenedPotentialFactor returns the factor p(r) the masses of two stars have to be multiplied with to get the
+// potential energy -G*m1*m2*p(r) using the same kernel as softenedForceFactor. Without softening, p(r) is 1/r.
+func softenedPotentialFactor(kernel SofteningKernel, length float64, distanceSquared float64) float64 {
+	switch kernel {
+	case PlummerSoftening:
+		return 1 / math.Sqrt(distanceSquared+length*length)
+
+	case SplineSoftening:
+		var h float64 = splineRadiusFactor * length
+		var distance float64 = math.Sqrt(distanceSquared)
+		if distance >= h {
+			return 1 / distance
+		}
+
+		var u float64 = distance / h
+		if u < 0.5 {
+			return (2.8 - u*u*(5.333333333333+u*u*(6.4*u-9.6))) / h
+		}
+		return (3.2 - 0.066666666667/u - u*u*(10.666666666667+u*(-16+u*(9.6-2.133333333333*u)))) / h
+
+	default:
+		return 1 / math.Sqrt(distanceSquared)
+	}
+}
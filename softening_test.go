@@ -0,0 +1,176 @@
+// softening_test.go provides tests for softening.go
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// Softening keeps the force in between coincident stars finite
+func ExampleWithSoftening() {
+	s1 := NewStar2D(Vec2{0, 0}, Vec2{0, 0}, 1e5)
+	s2 := NewStar2D(Vec2{0, 0}, Vec2{0, 0}, 1e5)
+	s3 := NewStar2D(Vec2{1, 0}, Vec2{0, 0}, 1e5)
+
+	fmt.Println(CalcForce(s1, s2, WithSoftening(PlummerSoftening, 1)))
+	fmt.Printf("%.4f\n", CalcForce(s1, s3, WithSoftening(PlummerSoftening, 1)))
+	fmt.Printf("%.4f\n", CalcForce(s1, s3))
+	// Output:
+	// {0 0}
+	// {0.2359 0.0000}
+	// {0.6673 0.0000}
+}
+
+func TestCalcForce_softening(t *testing.T) {
+	G := 6.6726 * math.Pow(10, -11)
+	newtonian := func(distance float64) float64 {
+		return G / (distance * distance)
+	}
+
+	type args struct {
+		kernel   SofteningKernel
+		length   float64
+		distance float64
+	}
+	tests := []struct {
+		name string
+		args args
+		want float64
+	}{
+		{
+			name: "plummer softening of coincident stars",
+			args: args{kernel: PlummerSoftening, length: 1, distance: 0},
+			want: 0,
+		},
+		{
+			name: "plummer softening at the softening length",
+			args: args{kernel: PlummerSoftening, length: 2, distance: 2},
+			want: G * 2 / math.Pow(8, 1.5),
+		},
+		{
+			name: "plummer softening far away",
+			args: args{kernel: PlummerSoftening, length: 1, distance: 1e5},
+			want: newtonian(1e5),
+		},
+		{
+			name: "spline softening of coincident stars",
+			args: args{kernel: SplineSoftening, length: 1, distance: 0},
+			want: 0,
+		},
+		{
+			name: "spline softening inside the kernel",
+			args: args{kernel: SplineSoftening, length: 1, distance: 0.7},
+			want: G * 0.7 * (10.666666666667 + 0.0625*(8-38.4)) / math.Pow(2.8, 3),
+		},
+		{
+			name: "spline softening at the edge of the kernel",
+			args: args{kernel: SplineSoftening, length: 1, distance: 2.8},
+			want: newtonian(2.8),
+		},
+		{
+			name: "spline softening outside of the kernel",
+			args: args{kernel: SplineSoftening, length: 1, distance: 3},
+			want: newtonian(3),
+		},
+		{
+			name: "no softening",
+			args: args{kernel: NoSoftening, length: 1, distance: 0.5},
+			want: newtonian(0.5),
+		},
+		{
+			name: "plummer softening using a length of zero",
+			args: args{kernel: PlummerSoftening, length: 0, distance: 0.5},
+			want: newtonian(0.5),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s1 := NewStar2D(Vec2{0, 0}, Vec2{0, 0}, 1)
+			s2 := NewStar2D(Vec2{tt.args.distance, 0}, Vec2{0, 0}, 1)
+
+			got := CalcForce(s1, s2, WithSoftening(tt.args.kernel, tt.args.length))
+			if math.Abs(got.X-tt.want) > 1e-8*tt.want || got.Y != 0 {
+				t.Errorf("CalcForce() = %v, want %v", got, Vec2{tt.want, 0})
+			}
+		})
+	}
+}
+
+func TestSoftenedForceFactor_continuous(t *testing.T) {
+	for _, u := range []float64{0.5, 1} {
+		below := softenedForceFactor(SplineSoftening, 1, math.Pow(math.Nextafter(u, 0)*splineRadiusFactor, 2))
+		above := softenedForceFactor(SplineSoftening, 1, math.Pow(u*splineRadiusFactor, 2))
+		if math.Abs(below-above) > 1e-9*above {
+			t.Errorf("softenedForceFactor() = %v below u = %v, %v above", below, u, above)
+		}
+	}
+}
+
+func TestWithAdaptiveSoftening(t *testing.T) {
+
+	// the softening length of a star is stored in its velocity for this test
+	length := func(star Star2D) float64 {
+		return star.V.X
+	}
+
+	s1 := NewStar2D(Vec2{0, 0}, Vec2{0.1, 0}, 10)
+	s2 := NewStar2D(Vec2{1, 1}, Vec2{2, 0}, 20)
+
+	got := CalcForce(s1, s2, WithAdaptiveSoftening(PlummerSoftening, length))
+	want := CalcForce(s1, s2, WithSoftening(PlummerSoftening, 2))
+	if got != want {
+		t.Errorf("CalcForce() = %v, want %v", got, want)
+	}
+
+	reverse := CalcForce(s2, s1, WithAdaptiveSoftening(PlummerSoftening, length))
+	if got.X != -reverse.X || got.Y != -reverse.Y {
+		t.Errorf("CalcForce() = %v in reverse, want %v", reverse, got.Multiply(-1))
+	}
+}
+
+func TestNode_CalcAllForces_softening(t *testing.T) {
+	stars := randomStars(500, 100, 12)
+	root, err := BuildTree(stars)
+	if err != nil {
+		t.Fatalf("BuildTree() error = %v", err)
+	}
+
+	kernels := map[string]SofteningKernel{
+		"plummer softening": PlummerSoftening,
+		"spline softening":  SplineSoftening,
+	}
+	for name, kernel := range kernels {
+		t.Run(name, func(t *testing.T) {
+			for _, star := range stars[:20] {
+				want := Vec2{}
+				for _, other := range stars {
+					if other != star {
+						want = want.Add(CalcForce(star, other, WithSoftening(kernel, 5)))
+					}
+				}
+
+				// opening every cell results in the direct sum
+				got := root.CalcAllForces(star, 0, WithSoftening(kernel, 5))
+				if math.Hypot(got.X-want.X, got.Y-want.Y) > 1e-9*math.Hypot(want.X, want.Y) {
+					t.Errorf("Node.CalcAllForces() = %v, want %v", got, want)
+				}
+			}
+		})
+	}
+}
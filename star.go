@@ -28,6 +28,16 @@ func NewStar2D(c Vec2, v Vec2, m float64) Star2D {
 	return Star2D{C: c, V: v, M: m}
 }
 
+// Position returns the coordinates of the star, it makes Star2D a Body
+func (star Star2D) Position() Vec2 {
+	return star.C
+}
+
+// Mass returns the mass of the star, it makes Star2D a Body
+func (star Star2D) Mass() float64 {
+	return star.M
+}
+
 // InsideOf is a method that tests if the star it is applied on is in or outside of the given
 // BoundingBox. It returns true if the star is inside of the BoundingBox and false if it isn't.
 func (star Star2D) InsideOf(boundary BoundingBox) bool {
@@ -78,31 +88,17 @@ func (star *Star2D) Accelerate(a Vec2, t float64) {
 }
 
 // posX determines if the star is the positive x region of the given boundary. If it is,
-// the method returns true, if not, it returns false
+// the method returns true, if not, it returns false. The star is expected to be inside of the boundary,
+// so a star on the outer edge of the boundary still belongs to the region it touches.
 func (star Star2D) posX(boundary BoundingBox) bool {
-
-	// define shortcuts
-	bx := boundary.Center.X
-	bw := boundary.Width / 2
-
-	if star.C.X > bx && star.C.X < bx+bw {
-		return true
-	}
-	return false
+	return star.C.X > boundary.Center.X
 }
 
 // posY determines if the star is the positive y region of the given boundary. If it is,
-// the method returns true, if not, it returns false
+// the method returns true, if not, it returns false. The star is expected to be inside of the boundary,
+// so a star on the outer edge of the boundary still belongs to the region it touches.
 func (star Star2D) posY(boundary BoundingBox) bool {
-
-	// define shortcuts
-	by := boundary.Center.Y
-	bw := boundary.Width / 2
-
-	if star.C.Y > by && star.C.Y < by+bw {
-		return true
-	}
-	return false
+	return star.C.Y > boundary.Center.Y
 }
 
 // getRelativePosition returns the relative position of a star relative to the bounding
@@ -121,13 +117,16 @@ func (star Star2D) getRelativePosition(boundary BoundingBox) string {
 	return "SW"
 }
 
+// getRelativePositionInt returns the index of the quadrant (NW, NE, SW, SE) in the Subtrees of a node
+// the star is in. It is called for every level of the tree a star passes, so it avoids going through
+// the string returned by getRelativePosition.
 func (star Star2D) getRelativePositionInt(boundary BoundingBox) int {
-	quadrantMap := make(map[string]int)
-	quadrantMap["NW"] = 0
-	quadrantMap["NE"] = 1
-	quadrantMap["SW"] = 2
-	quadrantMap["SE"] = 3
-
-	QuadrantMapString := star.getRelativePosition(boundary)
-	return quadrantMap[QuadrantMapString]
+	quadrant := 0
+	if star.posX(boundary) == true {
+		quadrant++
+	}
+	if star.posY(boundary) == false {
+		quadrant += 2
+	}
+	return quadrant
 }
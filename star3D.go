@@ -0,0 +1,80 @@
+// star3D.go defines three-dimensional stars and actions that can be used on them
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+// Star3D defines a struct storing essential star information such as it's coordinate, velocity and mass in three
+// dimensions
+type Star3D struct {
+	C Vec3    `json:"C"` // coordinates of the star
+	V Vec3    `json:"V"` // velocity    of the star
+	M float64 `json:"M"` // mass        of the star
+}
+
+// NewStar3D returns a new star using the given arguments as values for the Star
+func NewStar3D(c Vec3, v Vec3, m float64) Star3D {
+	return Star3D{C: c, V: v, M: m}
+}
+
+// InsideOf is a method that tests if the star it is applied on is in or outside of the given
+// BoundingBox3D. It returns true if the star is strictly inside of the BoundingBox3D and false if it isn't.
+func (star Star3D) InsideOf(boundary BoundingBox3D) bool {
+	halfWidth := boundary.Width / 2
+	return star.C.X > boundary.Center.X-halfWidth && star.C.X < boundary.Center.X+halfWidth &&
+		star.C.Y > boundary.Center.Y-halfWidth && star.C.Y < boundary.Center.Y+halfWidth &&
+		star.C.Z > boundary.Center.Z-halfWidth && star.C.Z < boundary.Center.Z+halfWidth
+}
+
+// Copy Return a copy of the star by returning a star struct with the same values.
+func (star *Star3D) Copy() Star3D {
+	return Star3D{star.C, star.V, star.M}
+}
+
+// AccelerateVelocity accelerates the star with the acceleration a for the time t.
+// This changes the velocity of the star.
+func (star *Star3D) AccelerateVelocity(a Vec3, t float64) {
+	star.V = star.V.Add(a.Multiply(t))
+}
+
+// Move the star with it's velocity for the time t.
+// This changes the Position of the star.
+func (star *Star3D) Move(t float64) {
+	star.C = star.C.Add(star.V.Multiply(t))
+}
+
+// Accelerate and move the star with it's velocity and the acceleration a for the time t
+// This changes the position and the velocity of the star.
+func (star *Star3D) Accelerate(a Vec3, t float64) {
+	star.AccelerateVelocity(a, t)
+	star.Move(t)
+}
+
+// getRelativePositionInt returns the index of the octant in the Subtrees of a node the star is in. The first four
+// octants are the quadrants (NW, NE, SW, SE) above the center of the boundary, the last four the ones below it.
+// Like in two dimensions, a star on a center plane belongs to the negative side.
+func (star Star3D) getRelativePositionInt(boundary BoundingBox3D) int {
+	octant := 0
+	if star.C.X > boundary.Center.X {
+		octant++
+	}
+	if star.C.Y <= boundary.Center.Y {
+		octant += 2
+	}
+	if star.C.Z <= boundary.Center.Z {
+		octant += 4
+	}
+	return octant
+}
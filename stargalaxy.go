@@ -21,3 +21,13 @@ type Stargalaxy struct {
 	Star  Star2D
 	Index int64
 }
+
+// Position returns the coordinates of the star, it makes Stargalaxy a Body
+func (s Stargalaxy) Position() Vec2 {
+	return s.Star.C
+}
+
+// Mass returns the mass of the star, it makes Stargalaxy a Body
+func (s Stargalaxy) Mass() float64 {
+	return s.Star.M
+}
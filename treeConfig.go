@@ -0,0 +1,64 @@
+// treeConfig.go defines the configuration of the trees storing galaxies
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+// DefaultMaxDepth is the maximum depth a tree can be subdivided to if no other depth is configured
+const DefaultMaxDepth = 32
+
+// TreeConfig defines how a tree stores its stars. The zero value is a tree storing a single star per leaf
+// with a maximum depth of DefaultMaxDepth.
+type TreeConfig struct {
+
+	// MaxDepth is the depth at which leaves aren't subdivided anymore. If it is zero, DefaultMaxDepth is used.
+	MaxDepth int
+
+	// LeafCapacity is the amount of stars a leaf can hold before it gets subdivided. Once MaxDepth is reached,
+	// a leaf stores all further stars in its bucket instead of subdividing.
+	// A LeafCapacity of zero disables the buckets: every leaf holds a single star and inserting a star that
+	// would have to be stored below MaxDepth results in ErrMaxDepthExceeded.
+	LeafCapacity int
+
+	// AutoExpand lets the root grow when a star outside of its BoundingBox is inserted. The BoundingBox is doubled
+	// towards the star until the star fits, the existing tree becomes one of the Subtrees of the grown root.
+	AutoExpand bool
+}
+
+// NewTreeConfig returns a new tree configuration using the given maximum depth and leaf capacity
+func NewTreeConfig(maxDepth int, leafCapacity int) TreeConfig {
+	return TreeConfig{MaxDepth: maxDepth, LeafCapacity: leafCapacity}
+}
+
+// maxDepth returns the configured maximum depth or DefaultMaxDepth if it isn't configured
+func (config TreeConfig) maxDepth() int {
+	if config.MaxDepth <= 0 {
+		return DefaultMaxDepth
+	}
+	return config.MaxDepth
+}
+
+// capacity returns the amount of stars a leaf can hold before it has to be subdivided
+func (config TreeConfig) capacity() int {
+	if config.LeafCapacity < 1 {
+		return 1
+	}
+	return config.LeafCapacity
+}
+
+// buckets returns true if leaves at the maximum depth store all further stars in their bucket
+func (config TreeConfig) buckets() bool {
+	return config.LeafCapacity > 0
+}
@@ -0,0 +1,121 @@
+// units.go defines the unit systems the positions, velocities and masses of the stars are given in
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+import "math"
+
+// GravitationalConstant is the gravitational constant in SI units (m^3 kg^-1 s^-2)
+const GravitationalConstant = 6.6726e-11
+
+// Scales of commonly used units in SI units
+const (
+	Kiloparsec         = 3.0856775814913673e19 // m
+	SolarMass          = 1.98847e30            // kg
+	Gigayear           = 3.15576e16            // s (julian years)
+	KilometerPerSecond = 1e3                   // m/s
+)
+
+// Units defines a unit system using the size of its units of length, mass and time in SI units. The zero value
+// is treated as SIUnits.
+type Units struct {
+	Length float64 // unit of length in m
+	Mass   float64 // unit of mass in kg
+	Time   float64 // unit of time in s
+}
+
+var (
+	// SIUnits measures lengths in m, masses in kg and times in s
+	SIUnits = Units{Length: 1, Mass: 1, Time: 1}
+
+	// GalacticUnits measures lengths in kpc, masses in solar masses and times in Gyr. Velocities are measured in
+	// kpc/Gyr, which is roughly 0.978 km/s.
+	GalacticUnits = Units{Length: Kiloparsec, Mass: SolarMass, Time: Gigayear}
+
+	// NBodyUnits measures lengths in kpc and masses in 1e10 solar masses, the unit of time is chosen so that G = 1
+	NBodyUnits = NewNBodyUnits(Kiloparsec, 1e10*SolarMass)
+)
+
+// NewUnits returns a new unit system using the given units of length, mass and time in SI units
+func NewUnits(length float64, mass float64, time float64) Units {
+	return Units{Length: length, Mass: mass, Time: time}
+}
+
+// NewNBodyUnits returns a new unit system using the given units of length and mass in SI units. The unit of time
+// is chosen so that the gravitational constant is 1.
+func NewNBodyUnits(length float64, mass float64) Units {
+	return Units{
+		Length: length,
+		Mass:   mass,
+		Time:   math.Sqrt(length * length * length / (GravitationalConstant * mass)),
+	}
+}
+
+// orSI returns the unit system or SIUnits if it is the zero value
+func (u Units) orSI() Units {
+	if u == (Units{}) {
+		return SIUnits
+	}
+	return u
+}
+
+// G returns the gravitational constant in the unit system
+func (u Units) G() float64 {
+	u = u.orSI()
+	if u == SIUnits {
+		return GravitationalConstant
+	}
+	return GravitationalConstant * u.Mass * u.Time * u.Time / (u.Length * u.Length * u.Length)
+}
+
+// Velocity returns the unit of velocity of the unit system in m/s
+func (u Units) Velocity() float64 {
+	u = u.orSI()
+	return u.Length / u.Time
+}
+
+// ConvertStar converts the position, the velocity and the mass of the star from the unit system from to the
+// unit system to
+func ConvertStar(star Star2D, from Units, to Units) Star2D {
+	from, to = from.orSI(), to.orSI()
+
+	var length float64 = from.Length / to.Length
+	var velocity float64 = from.Velocity() / to.Velocity()
+
+	return Star2D{
+		C: star.C.Multiply(length),
+		V: star.V.Multiply(velocity),
+		M: star.M * from.Mass / to.Mass,
+	}
+}
+
+// ConvertStars converts all the given stars from the unit system from to the unit system to. The given slice isn't
+// modified, a new slice is returned.
+func ConvertStars(stars []Star2D, from Units, to Units) []Star2D {
+	converted := make([]Star2D, len(stars))
+	for i, star := range stars {
+		converted[i] = ConvertStar(star, from, to)
+	}
+	return converted
+}
+
+// WithUnits calculates the forces using the gravitational constant of the given unit system. The positions and the
+// masses of the stars have to be given in that unit system, the forces are returned in it.
+func WithUnits(units Units) ForceOption {
+	return func(options *forceOptions) {
+		options.units = units
+	}
+}
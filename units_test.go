@@ -0,0 +1,128 @@
+// units_test.go provides tests for units.go
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// The gravitational constant depends on the unit system
+func ExampleUnits_G() {
+	fmt.Println(SIUnits.G())
+	fmt.Printf("%.4e\n", GalacticUnits.G())
+	fmt.Printf("%.4f\n", NBodyUnits.G())
+	// Output:
+	// 6.6726e-11
+	// 4.4975e-06
+	// 1.0000
+}
+
+// Stars can be converted in between unit systems
+func ExampleConvertStars() {
+	stars := []Star2D{
+		NewStar2D(Vec2{8, 0}, Vec2{0, 220}, 1),
+	}
+
+	// convert the velocity from km/s into kpc/Gyr
+	stars[0].V = stars[0].V.Multiply(KilometerPerSecond / GalacticUnits.Velocity())
+
+	converted := ConvertStars(stars, GalacticUnits, NBodyUnits)
+	fmt.Printf("%.4f %.4f %.4e\n", converted[0].C, converted[0].V, converted[0].M)
+	// Output:
+	// {8.0000 0.0000} {0.0000 1.0609} 1.0000e-10
+}
+
+func TestUnits_G(t *testing.T) {
+	tests := []struct {
+		name  string
+		units Units
+		want  float64
+	}{
+		{
+			name:  "zero value",
+			units: Units{},
+			want:  GravitationalConstant,
+		},
+		{
+			name:  "SI units",
+			units: SIUnits,
+			want:  GravitationalConstant,
+		},
+		{
+			name:  "galactic units",
+			units: GalacticUnits,
+			want:  4.4975e-6,
+		},
+		{
+			name:  "N-body units",
+			units: NBodyUnits,
+			want:  1,
+		},
+		{
+			name:  "N-body units using the sun and the astronomical unit",
+			units: NewNBodyUnits(1.495978707e11, SolarMass),
+			want:  1,
+		},
+		{
+			name:  "units using km and hours",
+			units: NewUnits(1e3, 1, 3600),
+			want:  GravitationalConstant * 3600 * 3600 / 1e9,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.units.G(); math.Abs(got-tt.want) > 1e-4*tt.want {
+				t.Errorf("Units.G() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertStars(t *testing.T) {
+	stars := randomStars(100, 100, 13)
+
+	// converting back and forth results in the same stars
+	for _, units := range []Units{SIUnits, GalacticUnits, NBodyUnits, NewUnits(1e3, 1, 3600)} {
+		converted := ConvertStars(ConvertStars(stars, GalacticUnits, units), units, GalacticUnits)
+		for i := range stars {
+			if math.Abs(converted[i].C.X-stars[i].C.X) > 1e-12*math.Abs(stars[i].C.X) ||
+				math.Abs(converted[i].C.Y-stars[i].C.Y) > 1e-12*math.Abs(stars[i].C.Y) ||
+				math.Abs(converted[i].V.X-stars[i].V.X) > 1e-12*math.Abs(stars[i].V.X) ||
+				math.Abs(converted[i].V.Y-stars[i].V.Y) > 1e-12*math.Abs(stars[i].V.Y) ||
+				math.Abs(converted[i].M-stars[i].M) > 1e-12*stars[i].M {
+				t.Errorf("ConvertStars() = %v, want %v", converted[i], stars[i])
+			}
+		}
+	}
+
+	// the forces calculated in different unit systems are the same
+	s1 := NewStar2D(Vec2{1, 2}, Vec2{0, 0}, 1e6)
+	s2 := NewStar2D(Vec2{-3, 0.5}, Vec2{0, 0}, 2e6)
+	for _, units := range []Units{GalacticUnits, NBodyUnits} {
+		force := CalcForce(s1, s2, WithUnits(units))
+
+		// convert the force into SI units
+		force = force.Multiply(units.Mass * units.Length / (units.Time * units.Time))
+
+		want := CalcForce(ConvertStar(s1, units, SIUnits), ConvertStar(s2, units, SIUnits))
+		if math.Hypot(force.X-want.X, force.Y-want.Y) > 1e-9*math.Hypot(want.X, want.Y) {
+			t.Errorf("CalcForce() = %v, want %v", force, want)
+		}
+	}
+}
@@ -16,6 +16,8 @@
 
 package structs
 
+import "math"
+
 // Vec2 defines a vector
 type Vec2 struct {
 	X float64 `json:"X"`
@@ -30,17 +32,94 @@ func NewVec2(x float64, y float64) Vec2 {
 	}
 }
 
+// NewVec2Polar returns a new Vec2 using the given length and the angle measured counterclockwise from the x axis
+func NewVec2Polar(length float64, angle float64) Vec2 {
+	sin, cos := math.Sincos(angle)
+	return Vec2{length * cos, length * sin}
+}
+
 // Copy creates a copy of the vector
-func (v *Vec2) Copy() Vec2 {
+func (v Vec2) Copy() Vec2 {
 	return Vec2{v.X, v.Y}
 }
 
 // Multiply returns the product of the vector and a scalar s
-func (v *Vec2) Multiply(s float64) Vec2 {
+func (v Vec2) Multiply(s float64) Vec2 {
 	return Vec2{v.X * s, v.Y * s}
 }
 
 // Add returns the sum of this vector and the vector v2
-func (v *Vec2) Add(v2 Vec2) Vec2 {
+func (v Vec2) Add(v2 Vec2) Vec2 {
 	return Vec2{v.X + v2.X, v.Y + v2.Y}
 }
+
+// Sub returns the difference of this vector and the vector v2
+func (v Vec2) Sub(v2 Vec2) Vec2 {
+	return Vec2{v.X - v2.X, v.Y - v2.Y}
+}
+
+// Dot returns the dot product of this vector and the vector v2
+func (v Vec2) Dot(v2 Vec2) float64 {
+	return v.X*v2.X + v.Y*v2.Y
+}
+
+// Cross returns the z component of the cross product of this vector and the vector v2. It is positive if v2 points
+// counterclockwise of this vector.
+func (v Vec2) Cross(v2 Vec2) float64 {
+	return v.X*v2.Y - v.Y*v2.X
+}
+
+// Len returns the length of the vector
+func (v Vec2) Len() float64 {
+	return math.Sqrt(v.Len2())
+}
+
+// Len2 returns the squared length of the vector, it avoids the square root if only lengths are compared
+func (v Vec2) Len2() float64 {
+	return v.X*v.X + v.Y*v.Y
+}
+
+// Normalize returns the unit vector pointing in the direction of the vector. The zero vector is returned unchanged.
+func (v Vec2) Normalize() Vec2 {
+	length := v.Len()
+	if length == 0 {
+		return v
+	}
+	return Vec2{v.X / length, v.Y / length}
+}
+
+// Distance returns the distance between the points the vector and the vector v2 point to
+func (v Vec2) Distance(v2 Vec2) float64 {
+	return v.Sub(v2).Len()
+}
+
+// Rotate returns the vector rotated counterclockwise by the given angle
+func (v Vec2) Rotate(angle float64) Vec2 {
+	sin, cos := math.Sincos(angle)
+	return Vec2{v.X*cos - v.Y*sin, v.X*sin + v.Y*cos}
+}
+
+// Perp returns the vector rotated counterclockwise by 90 degrees
+func (v Vec2) Perp() Vec2 {
+	return Vec2{-v.Y, v.X}
+}
+
+// Lerp returns the linear interpolation between this vector (t = 0) and the vector v2 (t = 1)
+func (v Vec2) Lerp(v2 Vec2, t float64) Vec2 {
+	return Vec2{v.X + t*(v2.X-v.X), v.Y + t*(v2.Y-v.Y)}
+}
+
+// Equal returns true if both components of the vectors differ by at most the given tolerance
+func (v Vec2) Equal(v2 Vec2, tolerance float64) bool {
+	return math.Abs(v.X-v2.X) <= tolerance && math.Abs(v.Y-v2.Y) <= tolerance
+}
+
+// IsFinite returns true if none of the components of the vector is NaN or infinite
+func (v Vec2) IsFinite() bool {
+	return !math.IsNaN(v.X) && !math.IsNaN(v.Y) && !math.IsInf(v.X, 0) && !math.IsInf(v.Y, 0)
+}
+
+// Polar returns the length of the vector and its angle measured counterclockwise from the x axis in (-pi, pi]
+func (v Vec2) Polar() (float64, float64) {
+	return v.Len(), math.Atan2(v.Y, v.X)
+}
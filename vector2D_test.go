@@ -0,0 +1,155 @@
+// vector2D_test.go provides tests for vector2D.go
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// The methods of Vec2 can be chained, as all of them use value receivers
+func ExampleVec2() {
+	a := NewVec2(3, 4)
+	b := NewVec2(1, 0)
+
+	fmt.Println(a.Len(), a.Sub(b).Len2(), a.Dot(b), b.Cross(a))
+	fmt.Println(a.Normalize(), a.Add(b).Multiply(2), b.Perp())
+	// Output:
+	// 5 20 3 4
+	// {0.6 0.8} {8 8} {-0 1}
+}
+
+func TestVec2_arithmetic(t *testing.T) {
+	tests := []struct {
+		name string
+		got  Vec2
+		want Vec2
+	}{
+		{name: "add", got: Vec2{1, 2}.Add(Vec2{3, -5}), want: Vec2{4, -3}},
+		{name: "sub", got: Vec2{1, 2}.Sub(Vec2{3, -5}), want: Vec2{-2, 7}},
+		{name: "multiply", got: Vec2{1, -2}.Multiply(-3), want: Vec2{-3, 6}},
+		{name: "copy", got: Vec2{1, 2}.Copy(), want: Vec2{1, 2}},
+		{name: "normalize", got: Vec2{0, -2}.Normalize(), want: Vec2{0, -1}},
+		{name: "normalize zero", got: Vec2{}.Normalize(), want: Vec2{}},
+		{name: "perp", got: Vec2{2, 1}.Perp(), want: Vec2{-1, 2}},
+		{name: "lerp start", got: Vec2{1, 2}.Lerp(Vec2{3, 6}, 0), want: Vec2{1, 2}},
+		{name: "lerp middle", got: Vec2{1, 2}.Lerp(Vec2{3, 6}, 0.5), want: Vec2{2, 4}},
+		{name: "lerp end", got: Vec2{1, 2}.Lerp(Vec2{3, 6}, 1), want: Vec2{3, 6}},
+		{name: "rotate", got: Vec2{1, 0}.Rotate(math.Pi / 2), want: Vec2{0, 1}},
+		{name: "rotate clockwise", got: Vec2{1, 1}.Rotate(-math.Pi / 4), want: Vec2{math.Sqrt2, 0}},
+		{name: "polar", got: NewVec2Polar(2, -math.Pi/2), want: Vec2{0, -2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got.Equal(tt.want, 1e-15) == false {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVec2_scalars(t *testing.T) {
+	tests := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{name: "dot", got: Vec2{1, 2}.Dot(Vec2{3, -5}), want: -7},
+		{name: "dot perpendicular", got: Vec2{1, 2}.Dot(Vec2{1, 2}.Perp()), want: 0},
+		{name: "cross counterclockwise", got: Vec2{1, 0}.Cross(Vec2{0, 2}), want: 2},
+		{name: "cross clockwise", got: Vec2{0, 2}.Cross(Vec2{1, 0}), want: -2},
+		{name: "len", got: Vec2{-3, 4}.Len(), want: 5},
+		{name: "len2", got: Vec2{-3, 4}.Len2(), want: 25},
+		{name: "distance", got: Vec2{1, 1}.Distance(Vec2{4, -3}), want: 5},
+		{name: "rotation keeps the length", got: Vec2{3, 4}.Rotate(1.234).Len(), want: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if math.Abs(tt.got-tt.want) > 1e-14 {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVec2_Polar(t *testing.T) {
+	tests := []struct {
+		vector Vec2
+		length float64
+		angle  float64
+	}{
+		{vector: Vec2{1, 0}, length: 1, angle: 0},
+		{vector: Vec2{0, 2}, length: 2, angle: math.Pi / 2},
+		{vector: Vec2{-1, 0}, length: 1, angle: math.Pi},
+		{vector: Vec2{-1, -1}, length: math.Sqrt2, angle: -3 * math.Pi / 4},
+		{vector: Vec2{}, length: 0, angle: 0},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.vector), func(t *testing.T) {
+			length, angle := tt.vector.Polar()
+			if math.Abs(length-tt.length) > 1e-15 || math.Abs(angle-tt.angle) > 1e-15 {
+				t.Errorf("Polar() = (%v, %v), want (%v, %v)", length, angle, tt.length, tt.angle)
+			}
+			if back := NewVec2Polar(length, angle); back.Equal(tt.vector, 1e-15) == false {
+				t.Errorf("NewVec2Polar() = %v, want %v", back, tt.vector)
+			}
+		})
+	}
+}
+
+func TestVec2_Equal(t *testing.T) {
+	tests := []struct {
+		name      string
+		v         Vec2
+		v2        Vec2
+		tolerance float64
+		want      bool
+	}{
+		{name: "same", v: Vec2{1, 2}, v2: Vec2{1, 2}, want: true},
+		{name: "inside of the tolerance", v: Vec2{1, 2}, v2: Vec2{1.05, 1.95}, tolerance: 0.1, want: true},
+		{name: "outside of the tolerance", v: Vec2{1, 2}, v2: Vec2{1, 2.2}, tolerance: 0.1, want: false},
+		{name: "exact", v: Vec2{1, 2}, v2: Vec2{1, math.Nextafter(2, 3)}, want: false},
+		{name: "nan", v: Vec2{math.NaN(), 0}, v2: Vec2{math.NaN(), 0}, tolerance: 1, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.v.Equal(tt.v2, tt.tolerance); got != tt.want {
+				t.Errorf("Equal() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVec2_IsFinite(t *testing.T) {
+	tests := []struct {
+		vector Vec2
+		want   bool
+	}{
+		{vector: Vec2{1, -2}, want: true},
+		{vector: Vec2{math.NaN(), 0}, want: false},
+		{vector: Vec2{0, math.Inf(-1)}, want: false},
+		{vector: Vec2{math.MaxFloat64, -math.MaxFloat64}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.vector), func(t *testing.T) {
+			if got := tt.vector.IsFinite(); got != tt.want {
+				t.Errorf("IsFinite() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+// vector3D.go defines the three-dimensional vector and its arithmetic
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+import "math"
+
+// Vec3 defines a three-dimensional vector
+type Vec3 struct {
+	X float64 `json:"X"`
+	Y float64 `json:"Y"`
+	Z float64 `json:"Z"`
+}
+
+// NewVec3 returns a new Vec3 using the given coordinates
+func NewVec3(x float64, y float64, z float64) Vec3 {
+	return Vec3{
+		X: x,
+		Y: y,
+		Z: z,
+	}
+}
+
+// Copy creates a copy of the vector
+func (v Vec3) Copy() Vec3 {
+	return Vec3{v.X, v.Y, v.Z}
+}
+
+// Multiply returns the product of the vector and a scalar s
+func (v Vec3) Multiply(s float64) Vec3 {
+	return Vec3{v.X * s, v.Y * s, v.Z * s}
+}
+
+// Add returns the sum of this vector and the vector v2
+func (v Vec3) Add(v2 Vec3) Vec3 {
+	return Vec3{v.X + v2.X, v.Y + v2.Y, v.Z + v2.Z}
+}
+
+// Sub returns the difference of this vector and the vector v2
+func (v Vec3) Sub(v2 Vec3) Vec3 {
+	return Vec3{v.X - v2.X, v.Y - v2.Y, v.Z - v2.Z}
+}
+
+// Dot returns the dot product of this vector and the vector v2
+func (v Vec3) Dot(v2 Vec3) float64 {
+	return v.X*v2.X + v.Y*v2.Y + v.Z*v2.Z
+}
+
+// Cross returns the cross product of this vector and the vector v2
+func (v Vec3) Cross(v2 Vec3) Vec3 {
+	return Vec3{
+		X: v.Y*v2.Z - v.Z*v2.Y,
+		Y: v.Z*v2.X - v.X*v2.Z,
+		Z: v.X*v2.Y - v.Y*v2.X,
+	}
+}
+
+// Len returns the length of the vector
+func (v Vec3) Len() float64 {
+	return math.Sqrt(v.Len2())
+}
+
+// Len2 returns the squared length of the vector, it avoids the square root if only lengths are compared
+func (v Vec3) Len2() float64 {
+	return v.X*v.X + v.Y*v.Y + v.Z*v.Z
+}
+
+// Normalize returns the unit vector pointing in the direction of the vector. The zero vector is returned unchanged.
+func (v Vec3) Normalize() Vec3 {
+	length := v.Len()
+	if length == 0 {
+		return v
+	}
+	return Vec3{v.X / length, v.Y / length, v.Z / length}
+}
+
+// Distance returns the distance between the points the vector and the vector v2 point to
+func (v Vec3) Distance(v2 Vec3) float64 {
+	return v.Sub(v2).Len()
+}
+
+// Equal returns true if all components of the vectors differ by at most the given tolerance
+func (v Vec3) Equal(v2 Vec3, tolerance float64) bool {
+	return math.Abs(v.X-v2.X) <= tolerance && math.Abs(v.Y-v2.Y) <= tolerance && math.Abs(v.Z-v2.Z) <= tolerance
+}
+
+// IsFinite returns true if none of the components of the vector is NaN or infinite
+func (v Vec3) IsFinite() bool {
+	return Vec2{v.X, v.Y}.IsFinite() && !math.IsNaN(v.Z) && !math.IsInf(v.Z, 0)
+}
@@ -0,0 +1,72 @@
+// vector3D_test.go provides tests for vector3D.go
+// Copyright (C) 2019 Emile Hansmaennel
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package structs
+
+import (
+	"math"
+	"testing"
+)
+
+func TestVec3(t *testing.T) {
+	a := NewVec3(1, 2, 2)
+	b := NewVec3(0, -1, 4)
+
+	vectors := []struct {
+		name string
+		got  Vec3
+		want Vec3
+	}{
+		{name: "add", got: a.Add(b), want: Vec3{1, 1, 6}},
+		{name: "sub", got: a.Sub(b), want: Vec3{1, 3, -2}},
+		{name: "multiply", got: a.Multiply(-2), want: Vec3{-2, -4, -4}},
+		{name: "copy", got: a.Copy(), want: a},
+		{name: "cross", got: Vec3{1, 0, 0}.Cross(Vec3{0, 1, 0}), want: Vec3{0, 0, 1}},
+		{name: "cross anticommutes", got: b.Cross(a), want: a.Cross(b).Multiply(-1)},
+		{name: "normalize", got: a.Normalize(), want: Vec3{1.0 / 3, 2.0 / 3, 2.0 / 3}},
+		{name: "normalize zero", got: Vec3{}.Normalize(), want: Vec3{}},
+	}
+	for _, tt := range vectors {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got.Equal(tt.want, 1e-15) == false {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+
+	scalars := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{name: "dot", got: a.Dot(b), want: 6},
+		{name: "cross is perpendicular", got: a.Cross(b).Dot(a), want: 0},
+		{name: "len", got: a.Len(), want: 3},
+		{name: "len2", got: a.Len2(), want: 9},
+		{name: "distance", got: a.Distance(b), want: math.Sqrt(14)},
+	}
+	for _, tt := range scalars {
+		t.Run(tt.name, func(t *testing.T) {
+			if math.Abs(tt.got-tt.want) > 1e-14 {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+
+	if (Vec3{1, 2, math.Inf(1)}).IsFinite() || (Vec3{math.NaN(), 0, 0}).IsFinite() || a.IsFinite() == false {
+		t.Errorf("Vec3.IsFinite() doesn't detect non-finite components")
+	}
+}